@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strings"
 
 	"github.com/yuzuy/yoru/token"
@@ -43,9 +44,12 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// LetStatement binds Value to Name, or destructures it into Names when
+// written as let (a, b) = value.
 type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Names []*Identifier
 	Value Expression
 }
 
@@ -55,7 +59,15 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Name != nil {
+		out.WriteString(ls.Name.String())
+	} else {
+		names := make([]string, 0, len(ls.Names))
+		for _, n := range ls.Names {
+			names = append(names, n.String())
+		}
+		out.WriteString("(" + strings.Join(names, ", ") + ")")
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -88,6 +100,143 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+type DeferStatement struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string {
+	return ds.TokenLiteral() + " " + ds.Call.String() + ";"
+}
+
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+// YieldStatement hands a value to the consumer of a generator. It may only
+// appear in a function body, which makes the function a generator.
+type YieldStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	return ys.TokenLiteral() + " " + ys.Value.String() + ";"
+}
+
+// StructStatement declares a struct type with its fields and methods.
+type StructStatement struct {
+	Token   token.Token
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*FunctionLiteral
+}
+
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	var out bytes.Buffer
+
+	var fields []string
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	out.WriteString("struct " + ss.Name.String() + " { ")
+	out.WriteString(strings.Join(fields, ", "))
+	for _, m := range ss.Methods {
+		var params []string
+		for _, p := range m.Parameters {
+			params = append(params, p.String())
+		}
+		out.WriteString("; fn " + m.Name + "(" + strings.Join(params, ", ") + ") " + m.Body.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// EnumStatement declares an enum, a type whose values are one of its
+// variants. A variant with fields is a constructor; one without is a value.
+type EnumStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (es *EnumStatement) statementNode()       {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	var variants []string
+	for _, v := range es.Variants {
+		variant := v.Name.String()
+		if len(v.Fields) > 0 {
+			var fields []string
+			for _, f := range v.Fields {
+				fields = append(fields, f.String())
+			}
+			variant += "(" + strings.Join(fields, ", ") + ")"
+		}
+		variants = append(variants, variant)
+	}
+
+	out.WriteString("enum " + es.Name.String() + " { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+type TryStatement struct {
+	Token   token.Token
+	Block   *BlockStatement
+	Param   *Identifier
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(ts.Block.String())
+
+	if ts.Catch != nil {
+		out.WriteString("catch ")
+		if ts.Param != nil {
+			out.WriteString("(" + ts.Param.String() + ") ")
+		}
+		out.WriteString(ts.Catch.String())
+	}
+
+	if ts.Finally != nil {
+		out.WriteString("finally ")
+		out.WriteString(ts.Finally.String())
+	}
+
+	return out.String()
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -138,6 +287,25 @@ func (pe *PrefixExpression) String() string {
 	return out.String()
 }
 
+type PostfixExpression struct {
+	Token    token.Token
+	Left     Expression
+	Operator string
+}
+
+func (pe *PostfixExpression) expressionNode()      {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(pe.Operator)
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type InfixExpression struct {
 	Token    token.Token
 	Left     Expression
@@ -159,6 +327,29 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignExpression rebinds an existing variable, as in x = x + 1. Since
+// a let inside a block only shadows the outer binding, this is how a
+// block updates a variable of an enclosing scope.
+type AssignExpression struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Name.String())
+	out.WriteString(" = ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -228,6 +419,87 @@ func (ss *SwitchStatement) String() string {
 	return out.String()
 }
 
+// SelectStatement waits until one of its cases can send or receive on a
+// channel and runs that case, or runs Default if no case is ready.
+type SelectStatement struct {
+	Token   token.Token
+	Cases   []*SelectCase
+	Default []Statement
+}
+
+// SelectCase is a case of a select statement: send(ch, v), receive(ch),
+// or name = receive(ch), which binds the received value to Name.
+type SelectCase struct {
+	Token token.Token
+	Name  *Identifier
+	Call  *CallExpression
+	Send  bool
+	Block []Statement
+}
+
+func (ss *SelectStatement) statementNode()       {}
+func (ss *SelectStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SelectStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("select {")
+	for _, c := range ss.Cases {
+		out.WriteString("case ")
+		if c.Name != nil {
+			out.WriteString(c.Name.String() + " = ")
+		}
+		out.WriteString(c.Call.String())
+		out.WriteString(":")
+		for _, s := range c.Block {
+			out.WriteString(s.String())
+		}
+	}
+	if ss.Default != nil {
+		out.WriteString("default:")
+		for _, s := range ss.Default {
+			out.WriteString(s.String())
+		}
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// SpawnExpression runs Call concurrently and evaluates to a task that can
+// be waited on for the call's result.
+type SpawnExpression struct {
+	Token token.Token
+	Call  *CallExpression
+}
+
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string {
+	return se.TokenLiteral() + " " + se.Call.String()
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
@@ -252,19 +524,42 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// Identifier is a name. Type is the optional annotation of a name being
+// declared, as in let x: int or fn(x: int).
 type Identifier struct {
 	Token token.Token
 	Value string
+	Type  *TypeName
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) String() string {
+	if i.Type != nil {
+		return i.Value + ": " + i.Type.String()
+	}
+	return i.Value
+}
+
+// TypeName is the type in an annotation, such as int or Point. The
+// evaluator ignores annotations; they are read by the checker package.
+type TypeName struct {
+	Token token.Token
+	Name  string
+}
+
+func (tn *TypeName) TokenLiteral() string { return tn.Token.Literal }
+func (tn *TypeName) String() string       { return tn.Name }
 
+// FunctionLiteral is a function. Generator is set when its body contains
+// yield, so that calling it returns a generator instead of running it.
 type FunctionLiteral struct {
 	Token      token.Token
+	Name       string
 	Parameters []*Identifier
+	ReturnType *TypeName
 	Body       *BlockStatement
+	Generator  bool
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -281,20 +576,35 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// IntegerLiteral holds its value in Value, or in Big if it does not fit
+// in an int64.
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type DecimalLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (dl *DecimalLiteral) expressionNode()      {}
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+func (dl *DecimalLiteral) String() string       { return dl.Token.Literal + "d" }
+
 type Null struct {
 	Token token.Token
 }
@@ -343,9 +653,63 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+type TupleLiteral struct {
+	Token    token.Token
+	Elements []Expression
+}
+
+func (tl *TupleLiteral) expressionNode()      {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := make([]string, 0)
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(tl.Elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+type SetLiteral struct {
+	Token    token.Token
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := make([]string, 0)
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// HashPair is a key-value pair of a HashLiteral.
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+// HashLiteral keeps its pairs in source order.
 type HashLiteral struct {
 	Token token.Token
-	Pairs map[Expression]Expression
+	Pairs []HashPair
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -354,8 +718,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for k, v := range hl.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -384,3 +748,42 @@ func (ie *IndexExpression) String() string {
 
 	return out.String()
 }
+
+// MemberExpression accesses a field or method of a struct value, as in p.x.
+type MemberExpression struct {
+	Token  token.Token
+	Left   Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Left.String() + "." + me.Member.String() + ")"
+}
+
+type RangeLiteral struct {
+	Token     token.Token
+	Start     Expression
+	End       Expression
+	Step      Expression
+	Inclusive bool
+}
+
+func (rl *RangeLiteral) expressionNode()      {}
+func (rl *RangeLiteral) TokenLiteral() string { return rl.Token.Literal }
+func (rl *RangeLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(rl.Start.String())
+	out.WriteString(rl.TokenLiteral())
+	out.WriteString(rl.End.String())
+	if rl.Step != nil {
+		out.WriteString(" step ")
+		out.WriteString(rl.Step.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
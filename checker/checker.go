@@ -0,0 +1,697 @@
+// Package checker finds type errors in a Yoru program before it runs. It
+// infers the types of expressions where it can, checks them against the
+// optional annotations on let bindings, parameters and return values, and
+// reports operators applied to the wrong types, calls with the wrong
+// number of arguments and calls on values that are not functions. Values
+// whose type cannot be inferred are not checked.
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuzuy/yoru/ast"
+	"github.com/yuzuy/yoru/object"
+	"github.com/yuzuy/yoru/token"
+)
+
+// Error is a problem found by Check at a position in the source.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Pos)
+	}
+	return e.Message
+}
+
+// Check returns the type errors and the warnings in program, each in
+// source order. Warnings are problems that do not stop the program from
+// running: switch statements without a default whose cases name some but
+// not all of the variants of an enum.
+func Check(program *ast.Program) (errs, warnings []error) {
+	return New().Check(program)
+}
+
+// Checker checks programs in one top-level scope, so that each sees the
+// declarations of the ones before it, as the inputs of the REPL share an
+// environment.
+type Checker struct {
+	scope *scope
+}
+
+func New() *Checker {
+	return &Checker{scope: newScope(nil)}
+}
+
+// Check returns the type errors and the warnings in program like the
+// function Check, and keeps its top-level declarations for later calls.
+func (ch *Checker) Check(program *ast.Program) (errs, warnings []error) {
+	c := &checker{scope: ch.scope}
+	c.checkStatements(program.Statements)
+	return c.errors, c.warnings
+}
+
+type checker struct {
+	errors   []error
+	warnings []error
+	scope    *scope
+	// fn is the signature of the function being checked, or nil at the
+	// top level.
+	fn *Signature
+}
+
+type variable struct {
+	typ       *Type
+	annotated bool
+	// enum is the enum declaring the variable, as its name or, if variant
+	// is set, as one of its variants.
+	enum    *enumDecl
+	variant string
+}
+
+// enumDecl lists the variants of an enum, for checking that a switch
+// covers all of them.
+type enumDecl struct {
+	name     string
+	variants []string
+}
+
+type scope struct {
+	vars  map[string]*variable
+	types map[string]*Type
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: make(map[string]*variable), types: make(map[string]*Type), outer: outer}
+}
+
+func (s *scope) lookup(name string) (*variable, bool) {
+	for ; s != nil; s = s.outer {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) lookupType(name string) (*Type, bool) {
+	for ; s != nil; s = s.outer {
+		if t, ok := s.types[name]; ok {
+			return t, true
+		}
+	}
+	t, ok := builtinTypes[name]
+	return t, ok
+}
+
+func (c *checker) errorf(tok token.Token, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Pos: tok.Pos, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *checker) warnf(tok token.Token, format string, a ...interface{}) {
+	c.warnings = append(c.warnings, &Error{Pos: tok.Pos, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *checker) enterScope() { c.scope = newScope(c.scope) }
+func (c *checker) leaveScope() { c.scope = c.scope.outer }
+
+func (c *checker) declare(name string, t *Type, annotated bool) {
+	c.scope.vars[name] = &variable{typ: t, annotated: annotated}
+}
+
+// resolve returns the type named by an annotation, or nil if there is no
+// annotation.
+func (c *checker) resolve(tn *ast.TypeName) *Type {
+	if tn == nil {
+		return nil
+	}
+	t, ok := c.scope.lookupType(tn.Name)
+	if !ok {
+		c.errorf(tn.Token, "unknown type %s", tn.Name)
+		return Any
+	}
+	return t
+}
+
+// checkStatements checks stmts in the current scope and returns the type
+// of the value they produce. Structs and enums are declared first, so that
+// annotations and functions can use them before their declaration.
+func (c *checker) checkStatements(stmts []ast.Statement) *Type {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.StructStatement:
+			if c.checkTypeName("struct", stmt.Name) {
+				c.scope.types[stmt.Name.Value] = &Type{Name: stmt.Name.Value, Members: make(map[string]*Type)}
+			}
+		case *ast.EnumStatement:
+			if c.checkTypeName("enum", stmt.Name) {
+				c.scope.types[stmt.Name.Value] = &Type{Name: stmt.Name.Value}
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		if stmt, ok := stmt.(*ast.StructStatement); ok && !object.IsReservedTypeName(stmt.Name.Value) {
+			c.declareMembers(stmt)
+		}
+	}
+
+	result := Null
+	for _, stmt := range stmts {
+		result = c.checkStatement(stmt)
+	}
+	return result
+}
+
+// checkTypeName reports whether a struct or enum may be called name,
+// which a built-in type such as int or INTEGER may not.
+func (c *checker) checkTypeName(kind string, name *ast.Identifier) bool {
+	if object.IsReservedTypeName(name.Value) {
+		c.errorf(name.Token, "%s name %s is reserved for a built-in type", kind, name.Value)
+		return false
+	}
+	return true
+}
+
+func (c *checker) checkBlock(block *ast.BlockStatement) *Type {
+	if block == nil {
+		return Null
+	}
+	c.enterScope()
+	defer c.leaveScope()
+	return c.checkStatements(block.Statements)
+}
+
+func (c *checker) checkStatement(stmt ast.Statement) *Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.checkLetStatement(stmt)
+	case *ast.ReturnStatement:
+		t := c.checkExpression(stmt.ReturnValue)
+		if c.fn != nil {
+			c.checkResult(stmt.Token, t)
+		}
+	case *ast.ExpressionStatement:
+		return c.checkExpression(stmt.Expression)
+	case *ast.DeferStatement:
+		c.checkExpression(stmt.Call)
+	case *ast.ThrowStatement:
+		c.checkExpression(stmt.Value)
+	case *ast.YieldStatement:
+		c.checkExpression(stmt.Value)
+	case *ast.BlockStatement:
+		return c.checkBlock(stmt)
+	case *ast.StructStatement:
+		if !object.IsReservedTypeName(stmt.Name.Value) {
+			c.checkStructStatement(stmt)
+		}
+	case *ast.EnumStatement:
+		if !object.IsReservedTypeName(stmt.Name.Value) {
+			c.checkEnumStatement(stmt)
+		}
+	case *ast.SwitchStatement:
+		c.checkSwitchStatement(stmt)
+	case *ast.SelectStatement:
+		c.checkSelectStatement(stmt)
+	case *ast.ForStatement:
+		c.checkExpression(stmt.Iterable)
+		c.enterScope()
+		c.declare(stmt.Variable.Value, Any, false)
+		c.checkStatements(stmt.Body.Statements)
+		c.leaveScope()
+	case *ast.TryStatement:
+		c.checkBlock(stmt.Block)
+		if stmt.Catch != nil {
+			c.enterScope()
+			if stmt.Param != nil {
+				c.declare(stmt.Param.Value, Any, false)
+			}
+			c.checkStatements(stmt.Catch.Statements)
+			c.leaveScope()
+		}
+		c.checkBlock(stmt.Finally)
+	}
+	return Any
+}
+
+func (c *checker) checkLetStatement(stmt *ast.LetStatement) {
+	if stmt.Name == nil {
+		c.checkExpression(stmt.Value)
+		for _, name := range stmt.Names {
+			c.declare(name.Value, Any, false)
+		}
+		return
+	}
+
+	annotation := c.resolve(stmt.Name.Type)
+	var t *Type
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		// declare the function before checking its body so that it can
+		// call itself
+		t = &Type{Name: Fn.Name, Sig: c.signature(fl, fl.Name)}
+		c.declare(stmt.Name.Value, t, false)
+		c.checkFunctionBody(fl, t.Sig, nil)
+	} else {
+		t = c.checkExpression(stmt.Value)
+	}
+
+	if annotation == nil {
+		c.declare(stmt.Name.Value, t, false)
+		return
+	}
+	if !assignable(annotation, t) {
+		c.errorf(stmt.Name.Token, "cannot use %s as %s in let %s", t, annotation, stmt.Name.Value)
+	}
+	c.declare(stmt.Name.Value, annotation, true)
+}
+
+// declareMembers records the fields and method signatures of a struct
+// on the type of its values.
+func (c *checker) declareMembers(stmt *ast.StructStatement) {
+	inst := c.scope.types[stmt.Name.Value]
+	for _, f := range stmt.Fields {
+		inst.Members[f.Value] = Any
+		if t := c.resolve(f.Type); t != nil {
+			inst.Members[f.Value] = t
+		}
+	}
+	for _, m := range stmt.Methods {
+		inst.Members[m.Name] = &Type{Name: Fn.Name, Sig: c.signature(m, stmt.Name.Value+"."+m.Name)}
+	}
+}
+
+func (c *checker) checkStructStatement(stmt *ast.StructStatement) {
+	inst := c.scope.types[stmt.Name.Value]
+	ctor := &Type{Name: Struct.Name, Sig: &Signature{Name: stmt.Name.Value, Result: inst}}
+	for _, f := range stmt.Fields {
+		ctor.Sig.Params = append(ctor.Sig.Params, inst.Members[f.Value])
+		ctor.Sig.Fields = append(ctor.Sig.Fields, f.Value)
+	}
+	c.declare(stmt.Name.Value, ctor, false)
+
+	for _, m := range stmt.Methods {
+		c.checkFunctionBody(m, inst.Members[m.Name].Sig, inst)
+	}
+}
+
+func (c *checker) checkEnumStatement(stmt *ast.EnumStatement) {
+	value := c.scope.types[stmt.Name.Value]
+	enum := &Type{Name: Enum.Name, Members: make(map[string]*Type)}
+	decl := &enumDecl{name: stmt.Name.Value}
+	for _, v := range stmt.Variants {
+		variant := value
+		if len(v.Fields) > 0 {
+			sig := &Signature{Name: stmt.Name.Value + "." + v.Name.Value, Result: value}
+			for _, f := range v.Fields {
+				sig.Params = append(sig.Params, Any)
+				sig.Fields = append(sig.Fields, f.Value)
+			}
+			variant = &Type{Name: Fn.Name, Sig: sig}
+		}
+		enum.Members[v.Name.Value] = variant
+		c.declare(v.Name.Value, variant, false)
+		c.scope.vars[v.Name.Value].enum = decl
+		c.scope.vars[v.Name.Value].variant = v.Name.Value
+		decl.variants = append(decl.variants, v.Name.Value)
+	}
+	c.declare(stmt.Name.Value, enum, false)
+	c.scope.vars[stmt.Name.Value].enum = decl
+}
+
+// signature returns the signature given by the annotations of fl.
+func (c *checker) signature(fl *ast.FunctionLiteral, name string) *Signature {
+	if name == "" {
+		name = "function"
+	}
+	sig := &Signature{Name: name, Result: Any}
+	for _, p := range fl.Parameters {
+		t := Any
+		if p.Type != nil {
+			t = c.resolve(p.Type)
+		}
+		sig.Params = append(sig.Params, t)
+	}
+	if fl.Generator {
+		sig.Result = Generator
+		if t := c.resolve(fl.ReturnType); t != nil && !assignable(t, Generator) {
+			c.errorf(fl.ReturnType.Token, "cannot use generator as %s in return from %s", t, name)
+		}
+	} else if fl.ReturnType != nil {
+		sig.Result = c.resolve(fl.ReturnType)
+	}
+	return sig
+}
+
+// checkFunctionBody checks the body of fl against its signature sig. self
+// is the struct a method belongs to, or nil.
+func (c *checker) checkFunctionBody(fl *ast.FunctionLiteral, sig *Signature, self *Type) {
+	outer := c.fn
+	c.fn = sig
+	if fl.Generator {
+		// the values a generator returns are dropped
+		c.fn = &Signature{Name: sig.Name, Result: Any}
+	}
+	c.enterScope()
+	defer func() {
+		c.leaveScope()
+		c.fn = outer
+	}()
+
+	if self != nil {
+		c.declare("self", self, false)
+	}
+	for i, p := range fl.Parameters {
+		c.declare(p.Value, sig.Params[i], p.Type != nil)
+	}
+
+	result := c.checkStatements(fl.Body.Statements)
+	if n := len(fl.Body.Statements); n > 0 {
+		if es, ok := fl.Body.Statements[n-1].(*ast.ExpressionStatement); ok {
+			c.checkResult(es.Token, result)
+		}
+	}
+}
+
+// checkResult reports a value of type t returned from the current
+// function if it does not match the annotated return type.
+func (c *checker) checkResult(tok token.Token, t *Type) {
+	if !assignable(c.fn.Result, t) {
+		c.errorf(tok, "cannot use %s as %s in return from %s", t, c.fn.Result, c.fn.Name)
+	}
+}
+
+func (c *checker) checkExpression(exp ast.Expression) *Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.DecimalLiteral:
+		return Decimal
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Null:
+		return Null
+	case *ast.ArrayLiteral:
+		c.checkExpressions(exp.Elements)
+		return Array
+	case *ast.TupleLiteral:
+		c.checkExpressions(exp.Elements)
+		return Tuple
+	case *ast.SetLiteral:
+		c.checkExpressions(exp.Elements)
+		return Set
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			c.checkExpression(pair.Key)
+			c.checkExpression(pair.Value)
+		}
+		return Hash
+	case *ast.RangeLiteral:
+		c.checkExpression(exp.Start)
+		c.checkExpression(exp.End)
+		if exp.Step != nil {
+			c.checkExpression(exp.Step)
+		}
+		return Range
+	case *ast.Identifier:
+		if v, ok := c.scope.lookup(exp.Value); ok {
+			return v.typ
+		}
+		if _, ok := builtins[exp.Value]; ok {
+			return &Type{Name: Fn.Name}
+		}
+		return Any
+	case *ast.PrefixExpression:
+		return c.checkPrefixExpression(exp)
+	case *ast.PostfixExpression:
+		c.checkExpression(exp.Left)
+		return Any
+	case *ast.InfixExpression:
+		return c.checkInfixExpression(exp)
+	case *ast.AssignExpression:
+		return c.checkAssignExpression(exp)
+	case *ast.IfExpression:
+		c.checkExpression(exp.Condition)
+		consequence := c.checkBlock(exp.Consequence)
+		alternative := c.checkBlock(exp.Alternative)
+		if consequence == alternative {
+			return consequence
+		}
+		return Any
+	case *ast.FunctionLiteral:
+		sig := c.signature(exp, exp.Name)
+		c.checkFunctionBody(exp, sig, nil)
+		return &Type{Name: Fn.Name, Sig: sig}
+	case *ast.CallExpression:
+		return c.checkCallExpression(exp)
+	case *ast.SpawnExpression:
+		c.checkCallExpression(exp.Call)
+		return Task
+	case *ast.IndexExpression:
+		left := c.checkExpression(exp.Left)
+		index := c.checkExpression(exp.Index)
+		if m, ok := left.Members["[]"]; ok && m.Sig != nil {
+			return c.checkCall(exp.Token, m, []*Type{index})
+		}
+		return Any
+	case *ast.MemberExpression:
+		left := c.checkExpression(exp.Left)
+		if left.Members == nil {
+			return Any
+		}
+		if m, ok := left.Members[exp.Member.Value]; ok {
+			return m
+		}
+		if left.Name == Enum.Name {
+			c.errorf(exp.Member.Token, "%s has no variant %s", exp.Left, exp.Member.Value)
+		} else {
+			c.errorf(exp.Member.Token, "%s has no field or method %s", left, exp.Member.Value)
+		}
+		return Any
+	}
+	return Any
+}
+
+func (c *checker) checkExpressions(exps []ast.Expression) []*Type {
+	types := make([]*Type, len(exps))
+	for i, e := range exps {
+		types[i] = c.checkExpression(e)
+	}
+	return types
+}
+
+func (c *checker) checkPrefixExpression(exp *ast.PrefixExpression) *Type {
+	right := c.checkExpression(exp.Right)
+	switch {
+	case exp.Operator == "!":
+		return Bool
+	case right == Any:
+		return Any
+	case exp.Operator == "-" && (right == Int || right == Decimal):
+		return right
+	case exp.Operator == "~" && right == Int:
+		return Int
+	}
+	c.errorf(exp.Token, "unknown operator: %s%s", exp.Operator, right)
+	return Any
+}
+
+func (c *checker) checkInfixExpression(exp *ast.InfixExpression) *Type {
+	left := c.checkExpression(exp.Left)
+	right := c.checkExpression(exp.Right)
+
+	if m, ok := left.Members[exp.Operator]; ok && m.Sig != nil {
+		return c.checkCall(exp.Token, m, []*Type{right})
+	}
+
+	t, ok := infixType(exp.Operator, left, right)
+	if !ok {
+		if left != right {
+			c.errorf(exp.Token, "type mismatch: %s %s %s", left, exp.Operator, right)
+		} else {
+			c.errorf(exp.Token, "unknown operator: %s %s %s", left, exp.Operator, right)
+		}
+	}
+	return t
+}
+
+func (c *checker) checkAssignExpression(exp *ast.AssignExpression) *Type {
+	t := c.checkExpression(exp.Value)
+	v, ok := c.scope.lookup(exp.Name.Value)
+	if !ok {
+		return t
+	}
+	switch {
+	case v.annotated && !assignable(v.typ, t):
+		c.errorf(exp.Token, "cannot use %s as %s in assignment to %s", t, v.typ, exp.Name.Value)
+	case !v.annotated && v.typ != t:
+		// the variable may hold either type from here on
+		v.typ = Any
+	}
+	return t
+}
+
+func (c *checker) checkSwitchStatement(ss *ast.SwitchStatement) {
+	c.checkExpression(ss.Target)
+	if ss.Default == nil {
+		c.checkExhaustive(ss)
+	}
+	for i := 1; i <= len(ss.Cases); i++ {
+		cs := ss.Cases[i]
+		c.enterScope()
+		call, ok := cs.Condition.(*ast.CallExpression)
+		if enum, _ := c.caseVariant(cs.Condition); ok && enum != nil {
+			// a pattern such as Circle(r) binds the names of its fields
+			c.checkExpression(call.Function)
+			for _, arg := range call.Arguments {
+				if ident, ok := arg.(*ast.Identifier); ok {
+					c.declare(ident.Value, Any, false)
+				}
+			}
+		} else {
+			c.checkExpression(cs.Condition)
+		}
+		c.checkStatements(cs.Block)
+		c.leaveScope()
+	}
+	if ss.Default != nil {
+		c.enterScope()
+		c.checkStatements(ss.Default)
+		c.leaveScope()
+	}
+}
+
+func (c *checker) checkSelectStatement(ss *ast.SelectStatement) {
+	for _, cs := range ss.Cases {
+		c.checkCallExpression(cs.Call)
+		c.enterScope()
+		if cs.Name != nil {
+			c.declare(cs.Name.Value, Any, false)
+		}
+		c.checkStatements(cs.Block)
+		c.leaveScope()
+	}
+	if ss.Default != nil {
+		c.enterScope()
+		c.checkStatements(ss.Default)
+		c.leaveScope()
+	}
+}
+
+// checkExhaustive warns when every case of ss names a variant of the same
+// enum, as in case Circle, case Shape.Circle or case Circle(r), but some
+// variants of the enum are missing.
+func (c *checker) checkExhaustive(ss *ast.SwitchStatement) {
+	var enum *enumDecl
+	covered := make(map[string]bool)
+	for i := 1; i <= len(ss.Cases); i++ {
+		e, variant := c.caseVariant(ss.Cases[i].Condition)
+		if e == nil || (enum != nil && e != enum) {
+			return
+		}
+		enum = e
+		covered[variant] = true
+	}
+	if enum == nil {
+		return
+	}
+
+	var missing []string
+	for _, v := range enum.variants {
+		if !covered[v] {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		c.warnf(ss.Token, "switch on %s is not exhaustive: missing %s", enum.name, strings.Join(missing, ", "))
+	}
+}
+
+// caseVariant returns the enum and variant named by a case condition, or
+// a nil enum if the condition does not name one.
+func (c *checker) caseVariant(cond ast.Expression) (*enumDecl, string) {
+	if call, ok := cond.(*ast.CallExpression); ok {
+		cond = call.Function
+	}
+
+	switch cond := cond.(type) {
+	case *ast.Identifier:
+		if v, ok := c.scope.lookup(cond.Value); ok && v.variant != "" {
+			return v.enum, v.variant
+		}
+	case *ast.MemberExpression:
+		ident, ok := cond.Left.(*ast.Identifier)
+		if !ok {
+			return nil, ""
+		}
+		v, ok := c.scope.lookup(ident.Value)
+		if !ok || v.enum == nil || v.variant != "" {
+			return nil, ""
+		}
+		for _, name := range v.enum.variants {
+			if name == cond.Member.Value {
+				return v.enum, name
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (c *checker) checkCallExpression(exp *ast.CallExpression) *Type {
+	fn := c.checkExpression(exp.Function)
+	args := c.checkExpressions(exp.Arguments)
+
+	if ident, ok := exp.Function.(*ast.Identifier); ok {
+		if _, declared := c.scope.lookup(ident.Value); !declared {
+			if b, ok := builtins[ident.Value]; ok {
+				return c.checkBuiltinCall(exp.Token, ident.Value, b, args)
+			}
+		}
+	}
+	if !callable(fn) {
+		c.errorf(exp.Token, "cannot call %s", fn)
+		return Any
+	}
+	return c.checkCall(exp.Token, fn, args)
+}
+
+// checkCall checks a call of a value of type fn with arguments of the
+// given types and returns the type of its result.
+func (c *checker) checkCall(tok token.Token, fn *Type, args []*Type) *Type {
+	sig := fn.Sig
+	if sig == nil {
+		return Any
+	}
+	if len(args) != len(sig.Params) {
+		if sig.Fields != nil || fn.Name == Struct.Name {
+			c.errorf(tok, "%s requires %d fields (%s). got=%d",
+				sig.Name, len(sig.Params), strings.Join(sig.Fields, ", "), len(args))
+		} else {
+			c.errorf(tok, "%s requires %d arguments. got=%d", sig.Name, len(sig.Params), len(args))
+		}
+		return sig.Result
+	}
+	for i, arg := range args {
+		if !assignable(sig.Params[i], arg) {
+			c.errorf(tok, "cannot use %s as %s in argument %d to %s", arg, sig.Params[i], i+1, sig.Name)
+		}
+	}
+	return sig.Result
+}
+
+func (c *checker) checkBuiltinCall(tok token.Token, name string, b builtin, args []*Type) *Type {
+	if len(args) < b.min || (b.max >= 0 && len(args) > b.max) {
+		c.errorf(tok, "wrong number of arguments to %s. got=%d", name, len(args))
+		return b.result
+	}
+	if name == "len" && isUserType(args[0]) {
+		// a struct may define its own len
+		return Any
+	}
+	return b.result
+}
@@ -0,0 +1,182 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yuzuy/yoru/lexer"
+	"github.com/yuzuy/yoru/object"
+	"github.com/yuzuy/yoru/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x: int = 5; let y: string = \"a\"; let z: decimal = 1.5d;", nil},
+		{"let x: int = \"a\";", []string{"cannot use string as int in let x (1:5)"}},
+		{"let x: any = \"a\"; let y: int = x;", nil},
+		{"let x = 1; let y: string = x + 2;", []string{"cannot use int as string in let y (1:16)"}},
+		{"let x = 1d; let y: decimal = x * 2;", nil},
+		{"let x: nope = 1;", []string{"unknown type nope (1:8)"}},
+		{"let x: int = 1; x = \"a\";", []string{"cannot use string as int in assignment to x (1:19)"}},
+		{"let x = 1; x = \"a\"; let y: string = x;", nil},
+		{"1 + \"a\";", []string{"type mismatch: int + string (1:3)"}},
+		{"true + false;", []string{"unknown operator: bool + bool (1:6)"}},
+		{"\"ab\" * 3; [1] + [2]; #{1} | #{2}; 1 < 2d;", nil},
+		{"-\"a\";", []string{"unknown operator: -string (1:1)"}},
+		{"let f = fn(x: int) -> int { x }; f(1); f(\"a\");", []string{"cannot use string as int in argument 1 to f (1:41)"}},
+		{"let f = fn(x, y) { x }; f(1);", []string{"f requires 2 arguments. got=1 (1:26)"}},
+		{"let f = fn(n) { f(n, 1) };", []string{"f requires 1 arguments. got=2 (1:18)"}},
+		{"let f = fn() -> bool { 1 };", []string{"cannot use int as bool in return from f (1:24)"}},
+		{"let f = fn() -> bool { ret 1; };", []string{"cannot use int as bool in return from f (1:24)"}},
+		{"let f = fn(x) -> bool { if (x) { ret true; } false };", nil},
+		{"let f = fn() -> int { 1 }; let s: string = f();", []string{"cannot use int as string in let s (1:32)"}},
+		{"let f = fn(g: fn) { g(1, 2) }; f(fn(a: int) { a });", nil},
+		{"let f = fn(g: fn) { g }; f(1);", []string{"cannot use int as fn in argument 1 to f (1:27)"}},
+		{"let x = 1; x();", []string{"cannot call int (1:13)"}},
+		{"\"a\"(1);", []string{"cannot call string (1:4)"}},
+		{"len(1, 2); print(); push([]);", []string{
+			"wrong number of arguments to len. got=2 (1:4)",
+			"wrong number of arguments to push. got=1 (1:25)",
+		}},
+		{"let n: int = len([1]); let s: string = str(n);", nil},
+		{"let len = fn() { 1 }; len();", nil},
+		{"let f = fn(p: Point) -> int { p.x }; struct Point { x };", nil},
+		{"struct P { x; fn norm() -> int { self.x * self.x } }; let s: string = P(1).norm();",
+			[]string{"cannot use int as string in let s (1:59)"}},
+		{"struct P { x }; P(1, 2);", []string{"P requires 1 fields (x). got=2 (1:18)"}},
+		{"struct P { x: int }; P(\"a\"); let s: string = P(1).x;", []string{
+			"cannot use string as int in argument 1 to P (1:23)",
+			"cannot use int as string in let s (1:34)",
+		}},
+		{"struct P { x }; P(1).y;", []string{"P has no field or method y (1:22)"}},
+		{"struct P { x }; let p: P = P(1); let q: int = p;", []string{"cannot use P as int in let q (1:38)"}},
+		{"struct V { x; fn +(o: V) -> V { V(self.x + o.x) } }; let v: V = V(1) + V(2); V(1) + 1;",
+			[]string{"cannot use int as V in argument 1 to V.+ (1:83)"}},
+		{"enum E { A(x), B }; let e: E = A(1); let f: E = B; A();", []string{"E.A requires 1 fields (x). got=0 (1:53)"}},
+		{"enum E { A(x), B }; E.C;", []string{"E has no variant C (1:23)"}},
+		{"struct P { x }; enum E { A(x) }; let f = fn(g: fn) { g(1) }; f(P); f(A); f(E.A); f(len);", nil},
+		{"enum E { A(x) }; let a: int = A;", []string{"cannot use fn as int in let a (1:22)"}},
+		{"struct int { x }; let n: int = 1;", []string{"struct name int is reserved for a built-in type (1:8)"}},
+		{"enum string { A }; let s: string = \"a\";", []string{"enum name string is reserved for a built-in type (1:6)"}},
+		{"struct INTEGER { x }; enum any { A };", []string{
+			"struct name INTEGER is reserved for a built-in type (1:8)",
+			"enum name any is reserved for a built-in type (1:28)",
+		}},
+		{"enum E { A(x), B }; switch (A(1)) { case A(x): x + 1 case B: 0 };", nil},
+		{"switch 1 { case (1 + \"a\")(2): 3 };", []string{"type mismatch: int + string (1:20)"}},
+		{"let x: int = 1; if (true) { let x = \"a\"; x + \"b\"; }; x + 1;", nil},
+		{"let g = fn(n: int) -> generator { yield n; ret 1; }; let x: generator = g(1); next(x);", nil},
+		{"let g = fn() -> int { yield 1; };", []string{"cannot use generator as int in return from g (1:17)"}},
+		{"let g = fn() { yield 1 + \"a\"; };", []string{"type mismatch: int + string (1:24)"}},
+		{"for (i in 1..3) { i + 1 }; try { 1 } catch (e) { e + 1 };", nil},
+		{"let f = fn(x: int) { x }; let t: task = spawn f(1); let ch: channel = channel(); wait(t);", nil},
+		{"let f = fn(x: int) { x }; spawn f(\"a\");", []string{"cannot use string as int in argument 1 to f (1:34)"}},
+		{"let ch = channel(); select { case v = receive(ch): v + 1 case send(ch, 1): 1 + \"a\" default: 0 };",
+			[]string{"type mismatch: int + string (1:78)"}},
+		{"let t: int = spawn len([]);", []string{"cannot use task as int in let t (1:5)"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		errs, _ := Check(program)
+		if len(errs) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. expected=%q, got=%v", tt.input, tt.expected, errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != tt.expected[i] {
+				t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestBuiltinTypesMatchEvaluator(t *testing.T) {
+	if len(builtinTypes) != len(object.AnnotationTypes)+1 {
+		t.Errorf("wrong number of built-in types. expected=%d, got=%d", len(object.AnnotationTypes)+1, len(builtinTypes))
+	}
+	for name := range builtinTypes {
+		if !object.IsReservedTypeName(name) {
+			t.Errorf("built-in type %s is not reserved", name)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	shape := "enum Shape { Circle(r), Rect(w, h), Empty }\n"
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let f = fn(s) {\n  switch s {\n  case Circle(r): r\n  }\n}", []string{"switch on Shape is not exhaustive: missing Rect, Empty (3:3)"}},
+		{"switch Empty { case Circle: 1 case Shape.Rect: 2 case Empty: 3 };", nil},
+		{"switch Empty { case Shape.Circle(r): r case Rect(_, h): h };", []string{"switch on Shape is not exhaustive: missing Empty (2:1)"}},
+		{"switch Empty { case Circle: 1 default: 2 };", nil},
+		{"switch 1 { case 2: 3 };", nil},
+		{"let x = 1; switch Empty { case Circle: 1 case x: 2 };", nil},
+		{"enum Other { A }; switch A { case Circle: 1 case A: 2 };", nil},
+		{"if (true) { let Empty = 1; switch Empty { case Empty: 1 } };", nil},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(shape + tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		_, warnings := Check(program)
+		if len(warnings) != len(tt.expected) {
+			t.Errorf("wrong number of warnings for %q. expected=%q, got=%v", tt.input, tt.expected, warnings)
+			continue
+		}
+		for i, w := range warnings {
+			if w.Error() != tt.expected[i] {
+				t.Errorf("wrong warning for %q. expected=%q, got=%q", tt.input, tt.expected[i], w.Error())
+			}
+		}
+	}
+}
+
+func TestCheckerKeepsDeclarations(t *testing.T) {
+	inputs := []string{
+		"enum Shape { Circle, Rect }",
+		"let area = fn(x: int) -> int { x };",
+		"switch Circle { case Circle: 1 };",
+		"area(\"a\");",
+	}
+	expected := [][]string{
+		nil,
+		nil,
+		{"switch on Shape is not exhaustive: missing Rect (1:1)"},
+		{"cannot use string as int in argument 1 to area (1:5)"},
+	}
+
+	ch := New()
+	for i, input := range inputs {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", input, p.Errors())
+		}
+
+		errs, warnings := ch.Check(program)
+		var got []string
+		for _, err := range append(warnings, errs...) {
+			got = append(got, err.Error())
+		}
+		if strings.Join(got, "\n") != strings.Join(expected[i], "\n") {
+			t.Errorf("wrong problems for %q. expected=%q, got=%q", input, expected[i], got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package checker
+
+// Type is the static type of a value. Its Name is the one used in
+// annotations, such as int, fn or the name of a struct.
+type Type struct {
+	Name string
+	// Sig is the signature of a function, struct or enum variant whose
+	// definition is known, and nil for any other value.
+	Sig *Signature
+	// Members are the fields and methods of a struct value or the
+	// variants of an enum, when its definition is known.
+	Members map[string]*Type
+}
+
+func (t *Type) String() string { return t.Name }
+
+// Signature describes how a value can be called.
+type Signature struct {
+	Name   string
+	Params []*Type
+	// Fields names the parameters of a struct or variant constructor,
+	// for error messages.
+	Fields []string
+	Result *Type
+}
+
+var (
+	Any       = &Type{Name: "any"}
+	Int       = &Type{Name: "int"}
+	Decimal   = &Type{Name: "decimal"}
+	String    = &Type{Name: "string"}
+	Bool      = &Type{Name: "bool"}
+	Null      = &Type{Name: "null"}
+	Array     = &Type{Name: "array"}
+	Hash      = &Type{Name: "hash"}
+	Set       = &Type{Name: "set"}
+	Tuple     = &Type{Name: "tuple"}
+	Range     = &Type{Name: "range"}
+	Fn        = &Type{Name: "fn"}
+	ErrorVal  = &Type{Name: "error"}
+	Struct    = &Type{Name: "struct"}
+	Enum      = &Type{Name: "enum"}
+	Generator = &Type{Name: "generator"}
+	Task      = &Type{Name: "task"}
+	Channel   = &Type{Name: "channel"}
+)
+
+// builtinTypes are the types that can be named in an annotation without
+// being declared.
+var builtinTypes = map[string]*Type{}
+
+func init() {
+	for _, t := range []*Type{Any, Int, Decimal, String, Bool, Null, Array, Hash, Set, Tuple, Range, Fn, ErrorVal, Struct, Enum, Generator, Task, Channel} {
+		builtinTypes[t.Name] = t
+	}
+}
+
+// builtin describes a builtin function by the number of arguments it
+// takes, with max -1 for any number, and the type of its result.
+type builtin struct {
+	min, max int
+	result   *Type
+}
+
+var builtins = map[string]builtin{
+	"print":     {0, -1, Null},
+	"len":       {1, 1, Int},
+	"push":      {2, -1, Array},
+	"type":      {1, 1, String},
+	"tuple":     {0, -1, Tuple},
+	"add":       {2, -1, Set},
+	"remove":    {2, -1, Set},
+	"keys":      {1, 1, Array},
+	"values":    {1, 1, Array},
+	"to_json":   {1, 1, String},
+	"error":     {1, 2, ErrorVal},
+	"is_error":  {1, 1, Bool},
+	"parse_int": {1, 1, Any},
+	"decimal":   {1, 1, Any},
+	"round":     {2, 3, Decimal},
+	"divide":    {3, 4, Decimal},
+	"int":       {1, 1, Int},
+	"str":       {1, 1, String},
+	"next":      {1, 1, Any},
+	"wait":      {1, -1, Any},
+	"channel":   {0, 1, Channel},
+	"send":      {2, 2, Null},
+	"receive":   {1, 1, Any},
+	"close":     {1, 1, Null},
+	"sleep":     {1, 1, Null},
+}
+
+// isBuiltinType reports whether name is the name of a built-in type.
+func isBuiltinType(name string) bool {
+	_, ok := builtinTypes[name]
+	return ok
+}
+
+// isUserType reports whether t is the type of a struct or enum value,
+// whose operators may be defined by the program.
+func isUserType(t *Type) bool {
+	return !isBuiltinType(t.Name)
+}
+
+// assignable reports whether a value of type from can be used where an
+// annotation asks for type to. Anything that can be called, such as a
+// struct or variant constructor, is a fn.
+func assignable(to, from *Type) bool {
+	return to == Any || from == Any || to.Name == from.Name || (to.Name == Fn.Name && from.Sig != nil)
+}
+
+// callable reports whether a value of type t may be called.
+func callable(t *Type) bool {
+	return t == Any || t.Sig != nil || t.Name == Fn.Name || t.Name == Struct.Name
+}
+
+// infixType returns the type of left operator right. ok is false when the
+// evaluator would reject the operands.
+func infixType(operator string, left, right *Type) (result *Type, ok bool) {
+	switch operator {
+	case "==", "!=", "in":
+		return Bool, true
+	}
+	if left == Any || right == Any || isUserType(left) || isUserType(right) {
+		if operator == "<" || operator == ">" {
+			return Bool, true
+		}
+		return Any, true
+	}
+
+	numeric := func(t *Type) bool { return t == Int || t == Decimal }
+	switch operator {
+	case "<", ">":
+		switch {
+		case numeric(left) && numeric(right), left == right && (left == String || left == Array || left == Tuple):
+			return Bool, true
+		}
+	case "+", "-", "*", "/", "%", "**":
+		switch {
+		case left == Int && right == Int:
+			return Int, true
+		case numeric(left) && numeric(right):
+			return Decimal, true
+		case operator == "+" && left == right && (left == String || left == Array):
+			return left, true
+		case operator == "*" && (left == String || left == Array) && right == Int:
+			return left, true
+		case operator == "*" && left == Int && (right == String || right == Array):
+			return right, true
+		case operator == "-" && left == Set && right == Set:
+			return Set, true
+		}
+	case "&", "|", "^":
+		if left == right && (left == Int || left == Set) {
+			return left, true
+		}
+	case "<<", ">>":
+		if left == Int && right == Int {
+			return Int, true
+		}
+	default:
+		return Any, true
+	}
+	return Any, false
+}
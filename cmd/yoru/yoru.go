@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/yuzuy/yoru/ast"
+	"github.com/yuzuy/yoru/checker"
 	"github.com/yuzuy/yoru/evaluator"
 	"github.com/yuzuy/yoru/lexer"
 	"github.com/yuzuy/yoru/object"
@@ -16,6 +18,7 @@ import (
 )
 
 func main() {
+	flag.BoolVar(&evaluator.Debug, "debug", false, "include the Go stack in internal errors")
 	flag.Parse()
 
 	filename := flag.Arg(0)
@@ -26,21 +29,67 @@ func main() {
 		return
 	}
 
+	if filename == "check" {
+		if !check(flag.Arg(1)) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	program, ok := parseFile(filename)
+	if !ok {
+		return
+	}
+	_, warnings := checker.Check(program)
+	logAll(filename, "warning: ", warnings)
+	env := object.NewEnvironment()
+	if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		log.Println(err.Inspect())
+		fmt.Fprint(os.Stderr, err.StackTrace())
+		if err.GoStack != "" {
+			fmt.Fprint(os.Stderr, err.GoStack)
+		}
+	}
+}
+
+// check reports the type errors in filename found without running it,
+// and whether there were none.
+func check(filename string) bool {
+	program, ok := parseFile(filename)
+	if !ok {
+		return false
+	}
+
+	errs, warnings := checker.Check(program)
+	logAll(filename, "warning: ", warnings)
+	logAll(filename, "", errs)
+	return len(errs) == 0
+}
+
+// logAll logs the problems found in filename, each after prefix.
+func logAll(filename, prefix string, errs []error) {
+	for _, err := range errs {
+		log.Printf("%s: %s%s\n", filename, prefix, err)
+	}
+}
+
+// parseFile parses the Yoru source in filename, logging any errors.
+func parseFile(filename string) (*ast.Program, bool) {
 	if ext := filepath.Ext(filename); ext != ".yoru" {
 		log.Printf("invalid file extension %q\n", ext)
-		return
+		return nil, false
 	}
 	f, err := os.Open(filename)
 	if err != nil {
 		log.Println(err.Error())
-		return
+		return nil, false
 	}
 	defer f.Close()
 
 	content, err := ioutil.ReadAll(f)
 	if err != nil {
 		log.Println(err.Error())
-		return
+		return nil, false
 	}
 
 	l := lexer.New(string(content))
@@ -50,12 +99,9 @@ func main() {
 		for _, err := range p.Errors() {
 			log.Println(err.Error())
 		}
-		return
-	}
-	env := object.NewEnvironment()
-	if result := evaluator.Eval(program, env); result.Type() == object.ErrorObj {
-		log.Println(result.Inspect())
+		return nil, false
 	}
+	return program, true
 }
 
 func init() {
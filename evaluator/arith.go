@@ -0,0 +1,224 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/yuzuy/yoru/object"
+)
+
+// maxBigIntBits bounds the size of results of ** and << so that a script
+// cannot exhaust memory with a single expression.
+const maxBigIntBits = 1 << 24
+
+// maxSequenceLen bounds the length of strings and arrays built by the *
+// operator for the same reason.
+const maxSequenceLen = 1 << 26
+
+// maxDecimalPlaces bounds the places given to round and divide, as the
+// rounding builds a power of ten with that many digits.
+const maxDecimalPlaces = 1000
+
+// addInt returns a + b and reports whether the sum fits in an int64.
+func addInt(a, b int64) (int64, bool) {
+	c := a + b
+	return c, (c > a) == (b > 0)
+}
+
+// subInt returns a - b and reports whether the difference fits in an int64.
+func subInt(a, b int64) (int64, bool) {
+	c := a - b
+	return c, (c < a) == (b > 0)
+}
+
+// mulInt returns a * b and reports whether the product fits in an int64.
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return c, false
+	}
+	return c, c/b == a
+}
+
+// powInt computes base ** exp by repeated squaring for a non-negative exp
+// and reports whether the result fits in an int64.
+func powInt(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for {
+		if exp&1 == 1 {
+			var ok bool
+			if result, ok = mulInt(result, base); !ok {
+				return result, false
+			}
+		}
+		exp >>= 1
+		if exp == 0 {
+			return result, true
+		}
+		var ok bool
+		if base, ok = mulInt(base, base); !ok {
+			return base, false
+		}
+	}
+}
+
+// normalizeBigInt demotes v to an Integer when it fits in an int64, so that
+// every integer value has exactly one representation.
+func normalizeBigInt(v *big.Int) object.Object {
+	if v.IsInt64() {
+		return &object.Integer{Value: v.Int64()}
+	}
+	return &object.BigInt{Value: v}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return nil
+	}
+}
+
+func evalBigIntInfixExpression(operator string, left, right *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return normalizeBigInt(new(big.Int).Add(left, right))
+	case "-":
+		return normalizeBigInt(new(big.Int).Sub(left, right))
+	case "*":
+		return normalizeBigInt(new(big.Int).Mul(left, right))
+	case "/":
+		if right.Sign() == 0 {
+			return newErrorKind(object.ArithmeticErrorKind, "division by zero: %s / 0", left)
+		}
+		return normalizeBigInt(new(big.Int).Quo(left, right))
+	case "%":
+		if right.Sign() == 0 {
+			return newErrorKind(object.ArithmeticErrorKind, "division by zero: %s %% 0", left)
+		}
+		return normalizeBigInt(new(big.Int).Rem(left, right))
+	case "**":
+		if right.Sign() < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative exponent: %s ** %s", left, right)
+		}
+		if left.CmpAbs(big.NewInt(1)) > 0 && (!right.IsInt64() || right.Int64() > maxBigIntBits/int64(left.BitLen()-1)) {
+			return newErrorKind(object.ArithmeticErrorKind, "integer too large: %s ** %s", left, right)
+		}
+		return normalizeBigInt(new(big.Int).Exp(left, right, nil))
+	case "&":
+		return normalizeBigInt(new(big.Int).And(left, right))
+	case "|":
+		return normalizeBigInt(new(big.Int).Or(left, right))
+	case "^":
+		return normalizeBigInt(new(big.Int).Xor(left, right))
+	case "<<":
+		if right.Sign() < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative shift count: %s << %s", left, right)
+		}
+		if left.Sign() == 0 {
+			return &object.Integer{Value: 0}
+		}
+		if !right.IsInt64() || right.Int64() > maxBigIntBits-int64(left.BitLen()) {
+			return newErrorKind(object.ArithmeticErrorKind, "integer too large: %s << %s", left, right)
+		}
+		return normalizeBigInt(new(big.Int).Lsh(left, uint(right.Int64())))
+	case ">>":
+		if right.Sign() < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative shift count: %s >> %s", left, right)
+		}
+		if !right.IsInt64() || right.Int64() > int64(left.BitLen()) {
+			if left.Sign() < 0 {
+				return &object.Integer{Value: -1}
+			}
+			return &object.Integer{Value: 0}
+		}
+		return normalizeBigInt(new(big.Int).Rsh(left, uint(right.Int64())))
+	case "==":
+		return nativeBoolToBooleanObject(left.Cmp(right) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(left.Cmp(right) != 0)
+	case "<":
+		return nativeBoolToBooleanObject(left.Cmp(right) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(left.Cmp(right) > 0)
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", object.IntObj, operator, object.IntObj)
+	}
+}
+
+func isDecimalOperand(obj object.Object) bool {
+	return obj.Type() == object.DecimalObj || obj.Type() == object.IntObj
+}
+
+// toDecimal converts an integer or decimal operand to a Decimal, so that
+// mixed arithmetic such as 2 * 1.50d stays exact.
+func toDecimal(obj object.Object) *object.Decimal {
+	if d, ok := obj.(*object.Decimal); ok {
+		return d
+	}
+	return object.NewDecimalFromInt(toBigInt(obj))
+}
+
+func evalDecimalInfixExpression(operator string, left, right *object.Decimal) object.Object {
+	switch operator {
+	case "+":
+		return left.Add(right)
+	case "-":
+		return left.Sub(right)
+	case "*":
+		return left.Mul(right)
+	case "/":
+		if right.Unscaled.Sign() == 0 {
+			return newErrorKind(object.ArithmeticErrorKind, "division by zero: %s / %s", left.Inspect(), right.Inspect())
+		}
+		scale := DecimalScale
+		if left.Scale > scale {
+			scale = left.Scale
+		}
+		if right.Scale > scale {
+			scale = right.Scale
+		}
+		return left.Quo(right, scale, DecimalRounding)
+	case "%":
+		if right.Unscaled.Sign() == 0 {
+			return newErrorKind(object.ArithmeticErrorKind, "division by zero: %s %% %s", left.Inspect(), right.Inspect())
+		}
+		return left.Rem(right)
+	case "**":
+		if right.Scale != 0 || right.Unscaled.Sign() < 0 {
+			return newErrorKind(object.ValueErrorKind, "exponent must be a non-negative integer: %s ** %s", left.Inspect(), right.Inspect())
+		}
+		if !right.Unscaled.IsInt64() {
+			return newErrorKind(object.ArithmeticErrorKind, "integer too large: %s ** %s", left.Inspect(), right.Inspect())
+		}
+		// both the digits and the scale of the result grow with the
+		// exponent, even when the digits are only 1
+		exp := right.Unscaled.Int64()
+		if left.Scale > 0 && exp > maxBigIntBits/int64(left.Scale) {
+			return newErrorKind(object.ArithmeticErrorKind, "decimal scale too large: %s ** %s", left.Inspect(), right.Inspect())
+		}
+		if left.Unscaled.CmpAbs(big.NewInt(1)) > 0 && exp > maxBigIntBits/int64(left.Unscaled.BitLen()-1) {
+			return newErrorKind(object.ArithmeticErrorKind, "integer too large: %s ** %s", left.Inspect(), right.Inspect())
+		}
+		return &object.Decimal{
+			Unscaled: new(big.Int).Exp(left.Unscaled, right.Unscaled, nil),
+			Scale:    left.Scale * int(exp),
+		}
+	case "==":
+		return nativeBoolToBooleanObject(left.Cmp(right) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(left.Cmp(right) != 0)
+	case "<":
+		return nativeBoolToBooleanObject(left.Cmp(right) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(left.Cmp(right) > 0)
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", object.DecimalObj, operator, object.DecimalObj)
+	}
+}
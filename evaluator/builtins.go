@@ -1,8 +1,11 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
 	"strings"
+	"time"
 
 	"github.com/yuzuy/yoru/object"
 )
@@ -23,7 +26,7 @@ var builtIns = map[string]*object.BuiltIn{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -31,18 +34,24 @@ var builtIns = map[string]*object.BuiltIn{
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
+			case *object.Set:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.Tuple:
+				return &object.Integer{Value: int64(len(arg.Elements))}
 			default:
-				return newError("argument to `len` not supported. got %s", arg.Type())
+				return newErrorKind(object.TypeErrorKind, "argument to `len` not supported. got %s", arg.Type())
 			}
 		},
 	},
 	"push": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) < 2 {
-				return newError("wrong number of arguments. got=%d", len(args))
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d", len(args))
 			}
 			if args[0].Type() != object.ArrayObj {
-				return newError("the first argument to `push` must be ARRAY. got=%s", args[0].Type())
+				return newErrorKind(object.TypeErrorKind, "the first argument to `push` must be ARRAY. got=%s", args[0].Type())
 			}
 
 			array := args[0].(*object.Array)
@@ -55,4 +64,432 @@ var builtIns = map[string]*object.BuiltIn{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"type": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			return &object.String{Value: string(args[0].Type())}
+		},
+	},
+	"tuple": {
+		Fn: func(args ...object.Object) object.Object {
+			elements := make([]object.Object, len(args))
+			copy(elements, args)
+
+			return &object.Tuple{Elements: elements}
+		},
+	},
+	"add": {
+		Fn: func(args ...object.Object) object.Object {
+			return updateSet("add", args, (*object.Set).Add)
+		},
+	},
+	"remove": {
+		Fn: func(args ...object.Object) object.Object {
+			return updateSet("remove", args, func(s *object.Set, el object.Hashable) { s.Remove(el) })
+		},
+	},
+	"keys": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "argument to `keys` must be HASH. got=%s", args[0].Type())
+			}
+
+			return &object.Array{Elements: hash.Keys()}
+		},
+	},
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "argument to `values` must be HASH. got=%s", args[0].Type())
+			}
+
+			return &object.Array{Elements: hash.Values()}
+		},
+	},
+	"to_json": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			var out bytes.Buffer
+			if err := writeJSON(&out, args[0]); err != nil {
+				return newErrorKind(object.TypeErrorKind, "%s", err)
+			}
+
+			return &object.String{Value: out.String()}
+		},
+	},
+	"error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			msg, ok := args[0].(*object.String)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "the first argument to `error` must be STRING. got=%s", args[0].Type())
+			}
+			kind := object.ErrorKind
+			if len(args) == 2 {
+				k, ok := args[1].(*object.String)
+				if !ok {
+					return newErrorKind(object.TypeErrorKind, "the second argument to `error` must be STRING. got=%s", args[1].Type())
+				}
+				kind = k.Value
+			}
+
+			return newErrorValue(kind, "%s", msg.Value)
+		},
+	},
+	"is_error": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			return nativeBoolToBooleanObject(args[0].Type() == object.ErrorValueObj)
+		},
+	},
+	"parse_int": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "argument to `parse_int` must be STRING. got=%s", args[0].Type())
+			}
+
+			v, ok := new(big.Int).SetString(strings.TrimSpace(str.Value), 10)
+			if !ok {
+				return newErrorValue(object.ValueErrorKind, "invalid integer: %q", str.Value)
+			}
+
+			return normalizeBigInt(v)
+		},
+	},
+	"decimal": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Decimal:
+				return arg
+			case *object.Integer, *object.BigInt:
+				return toDecimal(arg)
+			case *object.String:
+				d, err := object.ParseDecimal(arg.Value)
+				if err != nil {
+					return newErrorValue(object.ValueErrorKind, "invalid decimal: %q", arg.Value)
+				}
+				return d
+			default:
+				return newErrorKind(object.TypeErrorKind, "argument to `decimal` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"round": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+
+			if !isDecimalOperand(args[0]) {
+				return newErrorKind(object.TypeErrorKind, "the first argument to `round` must be DECIMAL. got=%s", args[0].Type())
+			}
+			places, mode, err := roundingArgs("round", args, 1)
+			if err != nil {
+				return err
+			}
+
+			return toDecimal(args[0]).Rescale(places, mode)
+		},
+	},
+	"divide": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 && len(args) != 4 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=3 or 4", len(args))
+			}
+
+			for i, arg := range args[:2] {
+				if !isDecimalOperand(arg) {
+					return newErrorKind(object.TypeErrorKind, "the %s argument to `divide` must be DECIMAL. got=%s", ordinals[i], arg.Type())
+				}
+			}
+			places, mode, err := roundingArgs("divide", args, 2)
+			if err != nil {
+				return err
+			}
+
+			left, right := toDecimal(args[0]), toDecimal(args[1])
+			if right.Unscaled.Sign() == 0 {
+				return newErrorKind(object.ArithmeticErrorKind, "division by zero: %s / %s", left.Inspect(), right.Inspect())
+			}
+			return left.Quo(right, places, mode)
+		},
+	},
+	"int": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return arg
+			case *object.Decimal:
+				return normalizeBigInt(arg.Truncate())
+			default:
+				return newErrorKind(object.TypeErrorKind, "argument to `int` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"str": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+	"next": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			gen, ok := args[0].(*object.Generator)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "argument to `next` must be GENERATOR. got=%s", args[0].Type())
+			}
+
+			// an exhausted generator keeps producing null
+			v, ok := gen.Next()
+			if !ok {
+				return Null
+			}
+			return v
+		},
+	},
+	"wait": {
+		FrameFn: func(caller *object.Frame, args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=0, want=1 or more")
+			}
+			if task, ok := args[0].(*object.Task); ok && len(args) == 1 {
+				result, err := task.Wait(caller.Sched)
+				if err != nil {
+					return blockingError(err)
+				}
+				return result
+			}
+
+			// several tasks, or an array of them, give an array of results
+			tasks := args
+			if arr, ok := args[0].(*object.Array); ok && len(args) == 1 {
+				tasks = arr.Elements
+			}
+			results := make([]object.Object, len(tasks))
+			for i, arg := range tasks {
+				task, ok := arg.(*object.Task)
+				if !ok {
+					return newErrorKind(object.TypeErrorKind, "argument to `wait` must be TASK. got=%s", arg.Type())
+				}
+				result, err := task.Wait(caller.Sched)
+				if err != nil {
+					return blockingError(err)
+				}
+				results[i] = result
+				if isError(results[i]) {
+					return results[i]
+				}
+			}
+			return &object.Array{Elements: results}
+		},
+	},
+	"channel": {
+		FrameFn: func(caller *object.Frame, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+
+			size := int64(0)
+			if len(args) == 1 {
+				n, ok := args[0].(*object.Integer)
+				if !ok {
+					return newErrorKind(object.TypeErrorKind, "argument to `channel` must be INTEGER. got=%s", args[0].Type())
+				}
+				if n.Value < 0 || n.Value > maxSequenceLen {
+					return newErrorKind(object.ValueErrorKind, "invalid channel size: %d", n.Value)
+				}
+				size = n.Value
+			}
+			return object.NewChannel(caller.Sched, int(size))
+		},
+	},
+	"send": {
+		FrameFn: func(caller *object.Frame, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=2", len(args))
+			}
+			ch, ok := args[0].(*object.Channel)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "first argument to `send` must be CHANNEL. got=%s", args[0].Type())
+			}
+
+			if err := ch.Send(caller.Sched, args[1]); err != nil {
+				return blockingError(err)
+			}
+			return Null
+		},
+	},
+	"receive": {
+		FrameFn: func(caller *object.Frame, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			ch, ok := args[0].(*object.Channel)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "argument to `receive` must be CHANNEL. got=%s", args[0].Type())
+			}
+
+			// a closed and drained channel produces null
+			v, ok, err := ch.Receive(caller.Sched)
+			if err != nil {
+				return blockingError(err)
+			}
+			if !ok {
+				return Null
+			}
+			return v
+		},
+	},
+	"close": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Channel:
+				if !arg.Close() {
+					return newErrorKind(object.ValueErrorKind, "close of closed channel")
+				}
+				return Null
+			case *object.Generator:
+				return arg.Close()
+			default:
+				return newErrorKind(object.TypeErrorKind, "argument to `close` must be CHANNEL or GENERATOR. got=%s", args[0].Type())
+			}
+		},
+	},
+	"sleep": {
+		FrameFn: func(caller *object.Frame, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d, want=1", len(args))
+			}
+			ms, ok := args[0].(*object.Integer)
+			if !ok {
+				return newErrorKind(object.TypeErrorKind, "argument to `sleep` must be INTEGER. got=%s", args[0].Type())
+			}
+
+			// a task being cancelled is woken early
+			timer := time.NewTimer(time.Duration(ms.Value) * time.Millisecond)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				return Null
+			case <-caller.Sched.Stop():
+				return blockingError(object.ErrCancelled)
+			}
+		},
+	},
+}
+
+// overloadableBuiltIns are the builtins a struct can take over for its
+// values by defining a method of the same name, as in fn len().
+var overloadableBuiltIns = map[string]bool{"len": true}
+
+func init() {
+	for name, b := range builtIns {
+		b.Name = name
+	}
+}
+
+// updateSet returns a copy of the set in args[0] with update applied to
+// each of the remaining arguments, leaving the original set unchanged
+// like push does for arrays.
+func updateSet(name string, args []object.Object, update func(*object.Set, object.Hashable)) object.Object {
+	if len(args) < 2 {
+		return newErrorKind(object.ArgumentErrorKind, "wrong number of arguments. got=%d", len(args))
+	}
+	set, ok := args[0].(*object.Set)
+	if !ok {
+		return newErrorKind(object.TypeErrorKind, "the first argument to `%s` must be SET. got=%s", name, args[0].Type())
+	}
+
+	result := &object.Set{}
+	addElements(result, set.Elements(), nil)
+	for _, arg := range args[1:] {
+		el, ok := arg.(object.Hashable)
+		if !ok {
+			return newErrorKind(object.TypeErrorKind, "unusable as set element: %s", arg.Type())
+		}
+		update(result, el)
+	}
+
+	return result
+}
+
+var ordinals = []string{"first", "second", "third", "fourth"}
+
+// roundingArgs reads the decimal places at args[i] and the optional
+// rounding mode after it, which default to DecimalRounding, for the
+// builtin name.
+func roundingArgs(name string, args []object.Object, i int) (int, object.RoundingMode, *object.Error) {
+	places, ok := args[i].(*object.Integer)
+	if !ok {
+		return 0, 0, newErrorKind(object.TypeErrorKind, "the %s argument to `%s` must be INTEGER. got=%s", ordinals[i], name, args[i].Type())
+	}
+	if places.Value < 0 || places.Value > maxDecimalPlaces {
+		return 0, 0, newErrorKind(object.ArgumentErrorKind, "decimal places out of range: %d", places.Value)
+	}
+
+	mode := DecimalRounding
+	if len(args) > i+1 {
+		str, ok := args[i+1].(*object.String)
+		if !ok {
+			return 0, 0, newErrorKind(object.TypeErrorKind, "the %s argument to `%s` must be STRING. got=%s", ordinals[i+1], name, args[i+1].Type())
+		}
+		if mode, ok = object.ParseRoundingMode(str.Value); !ok {
+			return 0, 0, newErrorKind(object.ValueErrorKind, "unknown rounding mode: %q", str.Value)
+		}
+	}
+	return int(places.Value), mode, nil
+}
+
+// newErrorValue returns an error as a value for builtins that report
+// expected failures to the caller instead of aborting the script.
+func newErrorValue(kind, format string, a ...interface{}) *object.ErrorValue {
+	return &object.ErrorValue{Error: newErrorKind(kind, format, a...)}
 }
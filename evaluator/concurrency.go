@@ -0,0 +1,115 @@
+package evaluator
+
+import (
+	"github.com/yuzuy/yoru/ast"
+	"github.com/yuzuy/yoru/object"
+)
+
+// evalSpawnExpression evaluates the function and arguments of the call,
+// then makes the call on a new goroutine and returns its task.
+func evalSpawnExpression(se *ast.SpawnExpression, env *object.Environment) object.Object {
+	function := eval(se.Call.Function, env)
+	if isAbrupt(function) {
+		return function
+	}
+	args := evalExpressions(se.Call.Arguments, env)
+	if len(args) == 1 && isAbrupt(args[0]) {
+		return args[0]
+	}
+
+	var name string
+	switch fn := function.(type) {
+	case *object.Function:
+		name = fn.Name
+	case *object.BuiltIn:
+		name = fn.Name
+	case *object.StructType:
+		name = fn.Name
+	case *object.EnumVariant:
+		name = fn.Inspect()
+	default:
+		return withPos(newErrorKind(object.TypeErrorKind, "not a function: %s", function.Type()), se.Call.Token)
+	}
+
+	caller := env.Frame()
+	if err := caller.Sched.StartTask(); err != nil {
+		return withPos(blockingError(err), se.Token)
+	}
+	task := object.NewTask(caller.Sched, name)
+	go func() {
+		var result object.Object
+		defer caller.Sched.EndTask()
+		defer func() {
+			if r := recover(); r != nil {
+				result = panicError(r)
+			}
+			if result == nil {
+				result = Null
+			}
+			task.Finish(result)
+		}()
+
+		result = withPos(applyFunction(caller, se.Call.Token.Pos, function, args), se.Call.Token)
+	}()
+
+	return task
+}
+
+// evalSelectStatement waits until one of the cases can communicate, or
+// runs the default case if none can right away. If several cases are
+// ready, one of them is chosen at random.
+func evalSelectStatement(ss *ast.SelectStatement, env *object.Environment) object.Object {
+	cases := make([]object.SelectCase, 0, len(ss.Cases))
+	for _, c := range ss.Cases {
+		arg := eval(c.Call.Arguments[0], env)
+		if isAbrupt(arg) {
+			return arg
+		}
+		ch, ok := arg.(*object.Channel)
+		if !ok {
+			return withPos(newErrorKind(object.TypeErrorKind, "select case requires CHANNEL. got=%s", arg.Type()), c.Token)
+		}
+
+		sc := object.SelectCase{Chan: ch, Send: c.Send}
+		if c.Send {
+			sc.Value = eval(c.Call.Arguments[1], env)
+			if isAbrupt(sc.Value) {
+				return sc.Value
+			}
+		}
+		cases = append(cases, sc)
+	}
+
+	chosen, recv, recvOK, err := env.Frame().Sched.Select(cases, ss.Default == nil)
+	if err != nil {
+		return withPos(blockingError(err), ss.Token)
+	}
+	if chosen < 0 {
+		return evalBlockStatement(&ast.BlockStatement{Statements: ss.Default}, env)
+	}
+
+	c := ss.Cases[chosen]
+	caseEnv := object.NewEnclosedEnvironment(env)
+	if c.Name != nil {
+		var v object.Object = Null
+		if recvOK {
+			v = recv
+		}
+		caseEnv.Set(c.Name.Value, v)
+	}
+	return evalBlockStatement(&ast.BlockStatement{Statements: c.Block}, caseEnv)
+}
+
+// blockingError converts the failure of a channel or task operation.
+func blockingError(err error) *object.Error {
+	return newErrorKind(object.BlockingErrorKind(err), "%s", err)
+}
+
+// cancelled returns the Error ending the loop iteration of a task that is
+// being cancelled, or nil.
+func cancelled(frame *object.Frame) object.Object {
+	if frame.Sched.Stopping() {
+		return blockingError(object.ErrCancelled)
+	}
+	return nil
+}
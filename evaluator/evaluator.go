@@ -2,6 +2,10 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"runtime/debug"
+	"strings"
 
 	"github.com/yuzuy/yoru/ast"
 	"github.com/yuzuy/yoru/object"
@@ -14,30 +18,106 @@ var (
 	False = &object.Boolean{Value: false}
 )
 
-func Eval(node ast.Node, env *object.Environment) object.Object {
+// MaxCallDepth is the deepest nesting of Yoru function calls allowed
+// before evaluation fails, keeping runaway recursion from overflowing the
+// Go stack, which cannot be recovered from.
+var MaxCallDepth = 10000
+
+// DecimalScale is the number of fractional digits kept by decimal
+// division when neither operand has more, and DecimalRounding is how the
+// remaining digits are rounded. Scripts choose both for a single division
+// with the divide builtin, and the rounding of round with its third
+// argument.
+var (
+	DecimalScale    = 16
+	DecimalRounding = object.RoundHalfEven
+)
+
+// Debug makes errors converted from Go panics carry the Go stack trace.
+var Debug = false
+
+// Eval evaluates node in env. Go panics raised during evaluation, for
+// example by a malformed AST, are returned as an Error instead of crashing
+// the host program. Once the last Eval running in env returns, the tasks
+// it left running are cancelled, as described on object.Scheduler.
+func Eval(node ast.Node, env *object.Environment) (result object.Object) {
+	sched := env.Frame().Sched
+	sched.Begin()
+	defer sched.End()
+	defer func() {
+		if r := recover(); r != nil {
+			result = panicError(r)
+		}
+	}()
+
+	return eval(node, env)
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return eval(node.Expression, env)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
-		if isError(val) {
+		val := eval(node.Value, env)
+		if isAbrupt(val) {
 			return val
 		}
+		if node.Name == nil {
+			return withPos(destructure(node.Names, val, env), node.Token)
+		}
 		env.Set(node.Name.Value, val)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		val := eval(node.ReturnValue, env)
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.DeferStatement:
+		function := eval(node.Call.Function, env)
+		if isAbrupt(function) {
+			return function
+		}
+		args := evalExpressions(node.Call.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
+			return args[0]
+		}
+		env.Frame().Defer(function, args, node.Token.Pos)
+	case *ast.StructStatement:
+		st := evalStructStatement(node, env)
+		if isError(st) {
+			return st
+		}
+		env.Set(node.Name.Value, st)
+	case *ast.EnumStatement:
+		if err := evalEnumStatement(node, env); err != nil {
+			return err
+		}
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
+	case *ast.AssignExpression:
+		val := eval(node.Value, env)
+		if isAbrupt(val) {
+			return val
+		}
+		if !env.Assign(node.Name.Value, val) {
+			return withPos(newErrorKind(object.NameErrorKind, "identifier not found: %s", node.Name.Value), node.Name.Token)
+		}
+		return val
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return withPos(evalIdentifier(node, env), node.Token)
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
+	case *ast.DecimalLiteral:
+		d, err := object.ParseDecimal(node.Value)
+		if err != nil {
+			return withPos(newErrorKind(object.ValueErrorKind, "%s", err), node.Token)
+		}
+		return d
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -46,64 +126,125 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return Null
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isAbrupt(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
+	case *ast.SetLiteral:
+		return evalSetLiteral(node, env)
+	case *ast.TupleLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return &object.Tuple{Elements: elements}
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
+	case *ast.RangeLiteral:
+		return withPos(evalRangeLiteral(node, env), node.Token)
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
+		left := eval(node.Left, env)
+		if isAbrupt(left) {
 			return left
 		}
-		index := Eval(node.Index, env)
-		if isError(index) {
+		index := eval(node.Index, env)
+		if isAbrupt(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return withPos(evalIndexExpression(left, index, env.Frame(), node.Token.Pos), node.Token)
+	case *ast.MemberExpression:
+		left := eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		return withPos(evalMemberExpression(left, node.Member.Value), node.Member.Token)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
-		if isError(right) {
+		right := eval(node.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return withPos(evalPrefixExpression(node.Operator, right), node.Token)
+	case *ast.PostfixExpression:
+		left := eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		return withPos(evalPostfixExpression(node.Operator, left), node.Token)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
+		left := eval(node.Left, env)
+		if isAbrupt(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
-		if isError(right) {
+		right := eval(node.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return withPos(evalInfixExpression(node.Operator, left, right, env.Frame(), node.Token.Pos), node.Token)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 	case *ast.SwitchStatement:
 		return evalSwitchStatement(node, env)
+	case *ast.SelectStatement:
+		return evalSelectStatement(node, env)
+	case *ast.SpawnExpression:
+		return withPos(evalSpawnExpression(node, env), node.Token)
+	case *ast.ForStatement:
+		return withPos(evalForStatement(node, env), node.Token)
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.YieldStatement:
+		return withPos(evalYieldStatement(node, env), node.Token)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Name: node.Name, Parameters: params, Body: body, Env: env, Generator: node.Generator}
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
+		function := eval(node.Function, env)
+		if isAbrupt(function) {
 			return function
 		}
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && isAbrupt(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return withPos(applyFunction(env.Frame(), node.Token.Pos, function, args), node.Token)
 	}
 
 	return nil
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newErrorKind(kind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+func panicError(r interface{}) *object.Error {
+	err := newErrorKind(object.InternalErrorKind, "internal error: %v", r)
+	if Debug {
+		err.GoStack = string(debug.Stack())
+	}
+	return err
+}
+
+// withPos records the position of tok on obj if it is an Error that does
+// not know where it was raised yet.
+func withPos(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = tok.Pos
+	}
+	return obj
+}
+
+// isAbrupt reports whether obj must cut evaluation short: an Error, or a
+// ReturnValue produced inside an expression by the ? operator.
+func isAbrupt(obj object.Object) bool {
+	if obj != nil {
+		t := obj.Type()
+		return t == object.ErrorObj || t == object.ReturnValueObj
+	}
+	return false
 }
 
 func isError(obj object.Object) bool {
@@ -114,22 +255,50 @@ func isError(obj object.Object) bool {
 }
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
-	var result object.Object
+	result := evalProgramStatements(program.Statements, env)
+	return runDeferred(env.Frame(), result)
+}
 
-	for _, stmt := range program.Statements {
-		result = Eval(stmt, env)
+// evalProgramStatements runs the top-level statements, converting a Go
+// panic into an error as evalBody does, so that the program's deferred
+// calls still run.
+func evalProgramStatements(stmts []ast.Statement, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = panicError(r)
+		}
+	}()
 
-		switch result := result.(type) {
-		case *object.ReturnValue:
-			return result.Value
-		case *object.Error:
-			return result
+	for _, stmt := range stmts {
+		result = eval(stmt, env)
+
+		if rv, ok := result.(*object.ReturnValue); ok {
+			return rv.Value
+		}
+		if isError(result) {
+			break
 		}
 	}
 
 	return result
 }
 
+// runDeferred calls the functions deferred on frame in LIFO order. An error
+// from a deferred call replaces result unless result is already an error.
+func runDeferred(frame *object.Frame, result object.Object) object.Object {
+	for {
+		d, ok := frame.PopDeferred()
+		if !ok {
+			return result
+		}
+
+		v := applyFunction(frame, d.Pos, d.Fn, d.Args)
+		if isError(v) && !isError(result) {
+			result = v
+		}
+	}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -143,8 +312,10 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -162,46 +333,195 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.IntObj {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return normalizeBigInt(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInt:
+		return normalizeBigInt(new(big.Int).Neg(right.Value))
+	case *object.Decimal:
+		return right.Neg()
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: -%s", right.Type())
 	}
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInt:
+		return normalizeBigInt(new(big.Int).Not(right.Value))
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: ~%s", right.Type())
+	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func evalPostfixExpression(operator string, left object.Object) object.Object {
+	switch operator {
+	case "?":
+		if left.Type() == object.ErrorValueObj {
+			return &object.ReturnValue{Value: left}
+		}
+		return left
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s%s", left.Type(), operator)
+	}
+}
+
+// evalInfixExpression applies operator to left and right. Operators a
+// struct defines as methods are called from the frame caller at pos.
+func evalInfixExpression(operator string, left, right object.Object, caller *object.Frame, pos token.Position) object.Object {
+	if result, ok := evalOperatorMethod(operator, left, right, caller, pos); ok {
+		return result
+	}
+
 	switch {
+	case operator == "in":
+		return evalInExpression(left, right)
+	case isDecimalOperand(left) && isDecimalOperand(right) &&
+		(left.Type() == object.DecimalObj || right.Type() == object.DecimalObj):
+		return evalDecimalInfixExpression(operator, toDecimal(left), toDecimal(right))
 	case left.Type() == object.IntObj && right.Type() == object.IntObj:
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.StringObj && right.Type() == object.StringObj:
 		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.SetObj && right.Type() == object.SetObj:
+		return evalSetInfixExpression(operator, left, right)
+	case left.Type() == object.ArrayObj && right.Type() == object.ArrayObj && operator == "+":
+		return evalArrayConcatenation(left, right)
+	case operator == "*" && isSequence(left) && right.Type() == object.IntObj:
+		return evalRepetition(left, right)
+	case operator == "*" && left.Type() == object.IntObj && isSequence(right):
+		return evalRepetition(right, left)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
+	case operator == "<" || operator == ">":
+		return evalOrderingExpression(operator, left, right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorKind(object.TypeErrorKind, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalInExpression(left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.IntObj && right.Type() == object.RangeObj:
+		v, ok := left.(*object.Integer)
+		if !ok {
+			return False
+		}
+		return nativeBoolToBooleanObject(right.(*object.Range).Contains(v.Value))
+	case right.Type() == object.ArrayObj || right.Type() == object.TupleObj:
+		elements := right.(object.Iterable).Iterator()
+		for el, ok := elements.Next(); ok; el, ok = elements.Next() {
+			if object.Equal(left, el) {
+				return True
+			}
+		}
+		return False
+	case right.Type() == object.HashObj:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return newErrorKind(object.TypeErrorKind, "unusable as hash key: %s", left.Type())
+		}
+		_, ok = right.(*object.Hash).Get(key)
+		return nativeBoolToBooleanObject(ok)
+	case right.Type() == object.SetObj:
+		el, ok := left.(object.Hashable)
+		if !ok {
+			return newErrorKind(object.TypeErrorKind, "unusable as set element: %s", left.Type())
+		}
+		return nativeBoolToBooleanObject(right.(*object.Set).Contains(el))
+	case left.Type() == object.StringObj && right.Type() == object.StringObj:
+		return nativeBoolToBooleanObject(strings.Contains(right.(*object.String).Value, left.(*object.String).Value))
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s in %s", left.Type(), right.Type())
 	}
 }
 
+// evalIntegerInfixExpression computes on int64 values while the result
+// fits and falls back to arbitrary precision otherwise.
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+	l, lok := left.(*object.Integer)
+	r, rok := right.(*object.Integer)
+	if !lok || !rok {
+		return evalBigIntInfixExpression(operator, toBigInt(left), toBigInt(right))
+	}
+	leftVal, rightVal := l.Value, r.Value
+	promoted := func() object.Object {
+		return evalBigIntInfixExpression(operator, big.NewInt(leftVal), big.NewInt(rightVal))
+	}
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		v, ok := addInt(leftVal, rightVal)
+		if !ok {
+			return promoted()
+		}
+		return &object.Integer{Value: v}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		v, ok := subInt(leftVal, rightVal)
+		if !ok {
+			return promoted()
+		}
+		return &object.Integer{Value: v}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		v, ok := mulInt(leftVal, rightVal)
+		if !ok {
+			return promoted()
+		}
+		return &object.Integer{Value: v}
 	case "/":
+		if rightVal == 0 {
+			return newErrorKind(object.ArithmeticErrorKind, "division by zero: %d / 0", leftVal)
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return promoted()
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "%":
+		if rightVal == 0 {
+			return newErrorKind(object.ArithmeticErrorKind, "division by zero: %d %% 0", leftVal)
+		}
+		if rightVal == -1 {
+			// MinInt64 % -1 overflows in the intermediate division
+			return &object.Integer{Value: 0}
+		}
 		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		if rightVal < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative exponent: %d ** %d", leftVal, rightVal)
+		}
+		v, ok := powInt(leftVal, rightVal)
+		if !ok {
+			return promoted()
+		}
+		return &object.Integer{Value: v}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<":
+		if rightVal < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative shift count: %d << %d", leftVal, rightVal)
+		}
+		if rightVal >= 63 || (leftVal<<uint64(rightVal))>>uint64(rightVal) != leftVal {
+			return promoted()
+		}
+		return &object.Integer{Value: leftVal << uint64(rightVal)}
+	case ">>":
+		if rightVal < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative shift count: %d >> %d", leftVal, rightVal)
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -211,7 +531,7 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -226,43 +546,324 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 		return &object.Boolean{Value: leftVal == rightVal}
 	case "!=":
 		return &object.Boolean{Value: leftVal != rightVal}
+	case "<", ">":
+		return evalOrderingExpression(operator, left, right)
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalArrayConcatenation(left, right object.Object) object.Object {
+	leftElements := left.(*object.Array).Elements
+	rightElements := right.(*object.Array).Elements
+
+	elements := make([]object.Object, 0, len(leftElements)+len(rightElements))
+	elements = append(elements, leftElements...)
+	elements = append(elements, rightElements...)
+	return &object.Array{Elements: elements}
+}
+
+// evalSetInfixExpression evaluates union (|), intersection (&),
+// difference (-) and symmetric difference (^) of two sets.
+func evalSetInfixExpression(operator string, left, right object.Object) object.Object {
+	l := left.(*object.Set)
+	r := right.(*object.Set)
+
+	result := &object.Set{}
+	switch operator {
+	case "|":
+		addElements(result, l.Elements(), nil)
+		addElements(result, r.Elements(), nil)
+	case "&":
+		addElements(result, l.Elements(), func(el object.Hashable) bool { return r.Contains(el) })
+	case "-":
+		addElements(result, l.Elements(), func(el object.Hashable) bool { return !r.Contains(el) })
+	case "^":
+		addElements(result, l.Elements(), func(el object.Hashable) bool { return !r.Contains(el) })
+		addElements(result, r.Elements(), func(el object.Hashable) bool { return !l.Contains(el) })
+	case "==":
+		return nativeBoolToBooleanObject(object.Equal(l, r))
+	case "!=":
+		return nativeBoolToBooleanObject(!object.Equal(l, r))
+	default:
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	return result
+}
+
+// addElements adds the elements accepted by keep to set. A nil keep
+// accepts every element.
+func addElements(set *object.Set, elements []object.Object, keep func(object.Hashable) bool) {
+	for _, el := range elements {
+		h := el.(object.Hashable)
+		if keep == nil || keep(h) {
+			set.Add(h)
+		}
+	}
+}
+
+func isSequence(obj object.Object) bool {
+	return obj.Type() == object.StringObj || obj.Type() == object.ArrayObj
+}
+
+// evalRepetition evaluates seq * count for a string or an array.
+func evalRepetition(seq, count object.Object) object.Object {
+	if b, ok := count.(*object.BigInt); ok {
+		if b.Value.Sign() < 0 {
+			return newErrorKind(object.ValueErrorKind, "negative repeat count: %s", b.Inspect())
+		}
+		return newErrorKind(object.ValueErrorKind, "repeat count too large: %s", b.Inspect())
+	}
+	n := count.(*object.Integer).Value
+	if n < 0 {
+		return newErrorKind(object.ValueErrorKind, "negative repeat count: %d", n)
+	}
+
+	switch seq := seq.(type) {
+	case *object.String:
+		if n > 0 && int64(len(seq.Value)) > maxSequenceLen/n {
+			return newErrorKind(object.ValueErrorKind, "repeat count too large: %d", n)
+		}
+		return &object.String{Value: strings.Repeat(seq.Value, int(n))}
+	case *object.Array:
+		if len(seq.Elements) == 0 || n == 0 {
+			return &object.Array{Elements: []object.Object{}}
+		}
+		if int64(len(seq.Elements)) > maxSequenceLen/n {
+			return newErrorKind(object.ValueErrorKind, "repeat count too large: %d", n)
+		}
+		elements := make([]object.Object, 0, len(seq.Elements)*int(n))
+		for i := int64(0); i < n; i++ {
+			elements = append(elements, seq.Elements...)
+		}
+		return &object.Array{Elements: elements}
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s * %s", seq.Type(), count.Type())
+	}
+}
+
+// evalOrderingExpression evaluates < and > on any values that
+// object.Compare can order.
+func evalOrderingExpression(operator string, left, right object.Object) object.Object {
+	c, ok := object.Compare(left, right)
+	if !ok {
+		if left.Type() != right.Type() {
+			return newErrorKind(object.TypeErrorKind, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		}
+		return newErrorKind(object.TypeErrorKind, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	if operator == "<" {
+		return nativeBoolToBooleanObject(c < 0)
+	}
+	return nativeBoolToBooleanObject(c > 0)
+}
+
+func evalStructStatement(ss *ast.StructStatement, env *object.Environment) object.Object {
+	if err := checkTypeName("struct", ss.Name); err != nil {
+		return err
+	}
+
+	st := &object.StructType{
+		Name:       ss.Name.Value,
+		Fields:     make([]string, 0, len(ss.Fields)),
+		FieldTypes: make([]string, 0, len(ss.Fields)),
+		Methods:    make(map[string]*object.Function, len(ss.Methods)),
+	}
+	for _, f := range ss.Fields {
+		st.Fields = append(st.Fields, f.Value)
+		typ := ""
+		if f.Type != nil {
+			typ = f.Type.Name
+		}
+		st.FieldTypes = append(st.FieldTypes, typ)
+	}
+	for _, m := range ss.Methods {
+		st.Methods[m.Name] = &object.Function{
+			Name:       st.Name + "." + m.Name,
+			Parameters: m.Parameters,
+			Body:       m.Body,
+			Env:        env,
+			Generator:  m.Generator,
+		}
+	}
+
+	return st
+}
+
+// evalEnumStatement binds the enum and each of its variants in env. A
+// variant with fields is bound to its constructor, one without to its
+// only value.
+func evalEnumStatement(es *ast.EnumStatement, env *object.Environment) *object.Error {
+	if err := checkTypeName("enum", es.Name); err != nil {
+		return err
+	}
+
+	et := &object.EnumType{Name: es.Name.Value}
+	for _, v := range es.Variants {
+		variant := &object.EnumVariant{Enum: et, Name: v.Name.Value}
+		for _, f := range v.Fields {
+			variant.Fields = append(variant.Fields, f.Value)
+		}
+		et.Variants = append(et.Variants, variant)
+	}
+
+	env.Set(et.Name, et)
+	for _, v := range et.Variants {
+		env.Set(v.Name, enumVariantObject(v))
+	}
+	return nil
+}
+
+// checkTypeName rejects a struct or enum name reserved for a built-in
+// type.
+func checkTypeName(kind string, name *ast.Identifier) *object.Error {
+	if !object.IsReservedTypeName(name.Value) {
+		return nil
+	}
+	err := newErrorKind(object.NameErrorKind, "%s name %s is reserved for a built-in type", kind, name.Value)
+	err.Pos = name.Token.Pos
+	return err
+}
+
+// hasType reports whether obj is a value of the type named typ in an
+// annotation. Other names are those of structs and enums.
+func hasType(obj object.Object, typ string) bool {
+	if typ == object.AnyType {
+		return true
+	}
+	types, ok := object.AnnotationTypes[typ]
+	if !ok {
+		return obj.Type() == object.Type(typ)
+	}
+	for _, t := range types {
+		if obj.Type() == t {
+			return true
+		}
+	}
+	return false
+}
+
+func enumVariantObject(v *object.EnumVariant) object.Object {
+	if len(v.Fields) == 0 {
+		return &object.EnumValue{Variant: v}
+	}
+	return v
+}
+
+// bindMethod returns method m with self bound to inst.
+func bindMethod(m *object.Function, inst *object.StructInstance) *object.Function {
+	env := object.NewEnclosedEnvironment(m.Env)
+	env.Set("self", inst)
+	return &object.Function{Name: m.Name, Parameters: m.Parameters, Body: m.Body, Env: env, Generator: m.Generator}
+}
+
+// operatorMethod returns the method called name of obj bound to obj, if
+// obj is a struct value whose struct defines it.
+func operatorMethod(obj object.Object, name string) (*object.Function, bool) {
+	inst, ok := obj.(*object.StructInstance)
+	if !ok {
+		return nil, false
+	}
+	m, ok := inst.Struct.Methods[name]
+	if !ok {
+		return nil, false
 	}
+	return bindMethod(m, inst), true
+}
+
+// evalOperatorMethod calls the method left defines for operator, such as
+// fn +(other). A struct defining only == also gets !=, and < and > fall
+// back to the other one on the right operand with the operands swapped.
+// ok is false when no method applies.
+func evalOperatorMethod(operator string, left, right object.Object, caller *object.Frame, pos token.Position) (result object.Object, ok bool) {
+	if m, ok := operatorMethod(left, operator); ok {
+		return applyFunction(caller, pos, m, []object.Object{right}), true
+	}
+
+	switch operator {
+	case "!=":
+		if m, ok := operatorMethod(left, "=="); ok {
+			equal := applyFunction(caller, pos, m, []object.Object{right})
+			if isAbrupt(equal) {
+				return equal, true
+			}
+			return nativeBoolToBooleanObject(!isTruthy(equal)), true
+		}
+	case "<":
+		if m, ok := operatorMethod(right, ">"); ok {
+			return applyFunction(caller, pos, m, []object.Object{left}), true
+		}
+	case ">":
+		if m, ok := operatorMethod(right, "<"); ok {
+			return applyFunction(caller, pos, m, []object.Object{left}), true
+		}
+	}
+	return nil, false
+}
+
+// evalMemberExpression looks up a field of a struct or enum value, a
+// method of a struct value bound to the value as self, a variant of an
+// enum, or a field of a caught error.
+func evalMemberExpression(left object.Object, name string) object.Object {
+	switch left := left.(type) {
+	case *object.StructInstance:
+		if v, ok := left.Field(name); ok {
+			return v
+		}
+		if m, ok := left.Struct.Methods[name]; ok {
+			return bindMethod(m, left)
+		}
+	case *object.EnumValue:
+		if v, ok := left.Field(name); ok {
+			return v
+		}
+	case *object.EnumType:
+		if v, ok := left.Variant(name); ok {
+			return enumVariantObject(v)
+		}
+		return newErrorKind(object.TypeErrorKind, "%s has no variant %s", left.Name, name)
+	case *object.ErrorValue:
+		if v, ok := errorField(left.Error, name); ok {
+			return v
+		}
+	}
+
+	return newErrorKind(object.TypeErrorKind, "%s has no field or method %s", left.Type(), name)
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	condition := eval(ie.Condition, env)
+	if isAbrupt(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return eval(ie.Alternative, env)
 	} else {
 		return Null
 	}
 }
 
 func evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) object.Object {
+	target := eval(ss.Target, env)
+	if isAbrupt(target) {
+		return target
+	}
+
 	for i := 1; i <= len(ss.Cases); i++ {
-		comparative := &ast.InfixExpression{
-			Token:    token.Token{Type: token.EQ, Literal: "=="},
-			Left:     ss.Target,
-			Operator: "==",
-			Right:    ss.Cases[i].Condition,
-		}
-		condition := Eval(comparative, env)
-		if isError(condition) {
-			return condition
+		caseEnv, matched := matchCase(ss.Cases[i].Condition, target, env, ss.Token.Pos)
+		if isAbrupt(matched) {
+			return matched
 		}
 
-		if isTruthy(condition) {
+		if isTruthy(matched) {
 			return evalBlockStatement(&ast.BlockStatement{
 				Statements: ss.Cases[i].Block,
-			}, env)
+			}, caseEnv)
 		}
 	}
 
@@ -275,6 +876,182 @@ func evalSwitchStatement(ss *ast.SwitchStatement, env *object.Environment) objec
 	return Null
 }
 
+// matchCase reports whether target matches the case condition cond, and
+// returns the environment to run the case in. A condition naming an enum
+// variant, such as Circle or Circle(r), matches values of that variant and
+// binds their fields to the given names; _ skips a field. Any other
+// condition matches a target equal to it.
+func matchCase(cond ast.Expression, target object.Object, env *object.Environment, pos token.Position) (*object.Environment, object.Object) {
+	if call, ok := cond.(*ast.CallExpression); ok {
+		fn := eval(call.Function, env)
+		if isAbrupt(fn) {
+			return env, fn
+		}
+		if variant, ok := fn.(*object.EnumVariant); ok {
+			return matchVariantPattern(variant, call, target, env)
+		}
+	}
+
+	value := eval(cond, env)
+	if isAbrupt(value) {
+		return env, value
+	}
+	if variant, ok := value.(*object.EnumVariant); ok {
+		ev, ok := target.(*object.EnumValue)
+		return env, nativeBoolToBooleanObject(ok && ev.Variant == variant)
+	}
+
+	return env, evalInfixExpression("==", target, value, env.Frame(), pos)
+}
+
+func matchVariantPattern(variant *object.EnumVariant, pattern *ast.CallExpression, target object.Object, env *object.Environment) (*object.Environment, object.Object) {
+	if len(pattern.Arguments) != len(variant.Fields) {
+		return env, withPos(newErrorKind(object.ArgumentErrorKind, "pattern for %s requires %d fields (%s). got=%d",
+			variant.Inspect(), len(variant.Fields), strings.Join(variant.Fields, ", "), len(pattern.Arguments)), pattern.Token)
+	}
+	names := make([]string, len(pattern.Arguments))
+	for i, arg := range pattern.Arguments {
+		ident, ok := arg.(*ast.Identifier)
+		if !ok {
+			return env, withPos(newErrorKind(object.TypeErrorKind, "pattern for %s must bind names. got %s",
+				variant.Inspect(), arg), pattern.Token)
+		}
+		names[i] = ident.Value
+	}
+
+	ev, ok := target.(*object.EnumValue)
+	if !ok || ev.Variant != variant {
+		return env, False
+	}
+
+	caseEnv := object.NewEnclosedEnvironment(env)
+	for i, name := range names {
+		if name != "_" {
+			caseEnv.Set(name, ev.Fields[i])
+		}
+	}
+	return caseEnv, True
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := eval(fs.Iterable, env)
+	if isAbrupt(iterable) {
+		return iterable
+	}
+
+	it, ok := iterable.(object.Iterable)
+	if !ok {
+		return newErrorKind(object.TypeErrorKind, "not iterable: %s", iterable.Type())
+	}
+
+	iter := it.Iterator()
+	frame := env.Frame()
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(*object.Error); ok {
+			return err
+		}
+		if err := cancelled(frame); err != nil {
+			return leaveLoop(iterable, err)
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		loopEnv.Set(fs.Variable.Value, v)
+
+		result := evalStatements(fs.Body.Statements, loopEnv)
+		if result != nil {
+			t := result.Type()
+			if t == object.ReturnValueObj || t == object.ErrorObj {
+				return leaveLoop(iterable, result)
+			}
+		}
+	}
+
+	return Null
+}
+
+// leaveLoop closes the generator walked by a for loop left early with
+// result, so that its cleanup runs right away. An Error the cleanup fails
+// with replaces a returned value.
+func leaveLoop(iterable, result object.Object) object.Object {
+	gen, ok := iterable.(*object.Generator)
+	if !ok {
+		return result
+	}
+	if err := gen.Close(); isError(err) && !isError(result) {
+		return err
+	}
+	return result
+}
+
+func evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
+	result := eval(ts.Block, env)
+
+	if err, ok := result.(*object.Error); ok && ts.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if ts.Param != nil {
+			catchEnv.Set(ts.Param.Value, &object.ErrorValue{Error: err})
+		}
+		result = evalStatements(ts.Catch.Statements, catchEnv)
+	}
+
+	if ts.Finally != nil {
+		finally := eval(ts.Finally, env)
+		if finally != nil {
+			t := finally.Type()
+			if t == object.ReturnValueObj || t == object.ErrorObj {
+				return finally
+			}
+		}
+	}
+
+	return result
+}
+
+// evalYieldStatement hands the value to the consumer of the generator
+// running in env and waits for the next value to be requested.
+func evalYieldStatement(ys *ast.YieldStatement, env *object.Environment) object.Object {
+	val := eval(ys.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	frame := env.Frame()
+	if frame == nil || frame.Yield == nil {
+		return newErrorKind(object.ErrorKind, "yield outside generator")
+	}
+	if err := frame.Yield(val); err != nil {
+		return err
+	}
+	return Null
+}
+
+func evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := eval(ts.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	if ev, ok := val.(*object.ErrorValue); ok {
+		// the caught error may be thrown again, so each throw gets a copy
+		// with its own position and trace
+		err := *ev.Error
+		err.Pos = ts.Token.Pos
+		err.Trace = nil
+		return &err
+	}
+
+	return &object.Error{
+		Kind:    object.ErrorKind,
+		Message: val.Inspect(),
+		Pos:     ts.Token.Pos,
+		Value:   val,
+	}
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case Null:
@@ -288,41 +1065,69 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func evalIndexExpression(left, index object.Object) object.Object {
+func evalIndexExpression(left, index object.Object, caller *object.Frame, pos token.Position) object.Object {
+	if m, ok := operatorMethod(left, "[]"); ok {
+		return applyFunction(caller, pos, m, []object.Object{index})
+	}
+
 	switch {
 	case left.Type() == object.ArrayObj && index.Type() == object.IntObj:
-		return evalArrayIndexExpression(left, index)
+		return evalArrayIndexExpression(left.(*object.Array).Elements, index)
+	case left.Type() == object.TupleObj && index.Type() == object.IntObj:
+		return evalArrayIndexExpression(left.(*object.Tuple).Elements, index)
 	case left.Type() == object.HashObj:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.RangeObj && index.Type() == object.IntObj:
+		return evalRangeIndexExpression(left, index)
+	case left.Type() == object.ErrorValueObj && index.Type() == object.StringObj:
+		return evalErrorValueIndexExpression(left, index)
 	default:
-		return newError("index operator not supported. %s[%s]", left.Type(), index.Type())
+		return newErrorKind(object.TypeErrorKind, "index operator not supported. %s[%s]", left.Type(), index.Type())
 	}
 }
 
+func evalSetLiteral(set *ast.SetLiteral, env *object.Environment) object.Object {
+	result := &object.Set{}
+
+	for _, e := range set.Elements {
+		el := eval(e, env)
+		if isAbrupt(el) {
+			return el
+		}
+
+		h, ok := el.(object.Hashable)
+		if !ok {
+			return newErrorKind(object.TypeErrorKind, "unusable as set element: %s", el.Type())
+		}
+		result.Add(h)
+	}
+
+	return result
+}
+
 func evalHashLiteral(hash *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	result := &object.Hash{}
 
-	for k, v := range hash.Pairs {
-		key := Eval(k, env)
-		if isError(key) {
+	for _, pair := range hash.Pairs {
+		key := eval(pair.Key, env)
+		if isAbrupt(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newErrorKind(object.TypeErrorKind, "unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(v, env)
-		if isError(value) {
+		value := eval(pair.Value, env)
+		if isAbrupt(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		result.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return result
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
@@ -330,34 +1135,171 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newErrorKind(object.TypeErrorKind, "unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObj.Pairs[key.HashKey()]
+	value, ok := hashObj.Get(key)
 	if !ok {
 		return Null
 	}
 
-	return pair.Value
+	return value
 }
 
-func evalArrayIndexExpression(array, index object.Object) object.Object {
-	arrayObj := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObj.Elements) - 1)
+func evalArrayIndexExpression(elements []object.Object, index object.Object) object.Object {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return Null
+	}
+	idx := i.Value
+	max := int64(len(elements) - 1)
 
 	if idx < 0 || idx > max {
 		return Null
 	}
 
-	return arrayObj.Elements[idx]
+	return elements[idx]
+}
+
+// destructure binds the elements of a tuple or an array to names, which
+// must match it in length.
+func destructure(names []*ast.Identifier, val object.Object, env *object.Environment) object.Object {
+	var elements []object.Object
+	switch val := val.(type) {
+	case *object.Tuple:
+		elements = val.Elements
+	case *object.Array:
+		elements = val.Elements
+	default:
+		return newErrorKind(object.TypeErrorKind, "cannot destructure %s", val.Type())
+	}
+	if len(elements) != len(names) {
+		return newErrorKind(object.ValueErrorKind, "cannot destructure %d values into %d names", len(elements), len(names))
+	}
+
+	for i, name := range names {
+		env.Set(name.Value, elements[i])
+	}
+	return nil
 }
 
+func evalRangeIndexExpression(rng, index object.Object) object.Object {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return Null
+	}
+
+	v, ok := rng.(*object.Range).At(i.Value)
+	if !ok {
+		return Null
+	}
+
+	return &object.Integer{Value: v}
+}
+
+func evalErrorValueIndexExpression(errVal, index object.Object) object.Object {
+	if v, ok := errorField(errVal.(*object.ErrorValue).Error, index.(*object.String).Value); ok {
+		return v
+	}
+	return Null
+}
+
+// errorField returns the field name of a caught error, as read by e.name
+// or e["name"].
+func errorField(err *object.Error, name string) (object.Object, bool) {
+	switch name {
+	case "message":
+		return &object.String{Value: err.Message}, true
+	case "kind":
+		return &object.String{Value: err.Kind}, true
+	case "line":
+		return &object.Integer{Value: int64(err.Pos.Line)}, true
+	case "column":
+		return &object.Integer{Value: int64(err.Pos.Column)}, true
+	case "value":
+		if err.Value == nil {
+			return Null, true
+		}
+		return err.Value, true
+	case "trace":
+		trace := make([]object.Object, 0, len(err.Trace))
+		for _, t := range err.Trace {
+			entry := &object.Hash{}
+			entry.Set(&object.String{Value: "function"}, &object.String{Value: t.Function})
+			entry.Set(&object.String{Value: "line"}, &object.Integer{Value: int64(t.Pos.Line)})
+			entry.Set(&object.String{Value: "column"}, &object.Integer{Value: int64(t.Pos.Column)})
+			trace = append(trace, entry)
+		}
+		return &object.Array{Elements: trace}, true
+	default:
+		return nil, false
+	}
+}
+
+func evalRangeLiteral(rl *ast.RangeLiteral, env *object.Environment) object.Object {
+	bounds := []ast.Expression{rl.Start, rl.End}
+	if rl.Step != nil {
+		bounds = append(bounds, rl.Step)
+	}
+
+	values := make([]int64, 0, len(bounds))
+	for _, b := range bounds {
+		v := eval(b, env)
+		if isAbrupt(v) {
+			return v
+		}
+		if _, ok := v.(*object.BigInt); ok {
+			return newErrorKind(object.ValueErrorKind, "range bound out of range: %s", v.Inspect())
+		}
+		i, ok := v.(*object.Integer)
+		if !ok {
+			return newErrorKind(object.TypeErrorKind, "range bound must be INTEGER. got=%s", v.Type())
+		}
+		values = append(values, i.Value)
+	}
+
+	r := &object.Range{Start: values[0], End: values[1], Step: 1, Inclusive: rl.Inclusive}
+	if rl.Step != nil {
+		if values[2] == 0 {
+			return newErrorKind(object.ValueErrorKind, "range step must not be zero")
+		}
+		r.Step = values[2]
+	}
+
+	return r
+}
+
+// evalBlockStatement evaluates bs in its own scope. A let inside the block
+// declares a new binding that shadows any outer binding of the same name
+// until the block ends, while assignment (x = v) updates the nearest
+// existing binding, which may live outside the block. Blocks that declare
+// nothing reuse env so that the common case does not allocate.
 func evalBlockStatement(bs *ast.BlockStatement, env *object.Environment) object.Object {
+	if declaresNames(bs.Statements) {
+		env = object.NewEnclosedEnvironment(env)
+	}
+
+	return evalStatements(bs.Statements, env)
+}
+
+func declaresNames(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt.(type) {
+		case *ast.LetStatement, *ast.StructStatement, *ast.EnumStatement:
+			return true
+		}
+	}
+
+	return false
+}
+
+// evalStatements runs stmts directly in env. It is used for bodies whose
+// scope has already been created, such as function calls and loop iterations.
+func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
-	for _, stmt := range bs.Statements {
-		result = Eval(stmt, env)
+	for _, stmt := range stmts {
+		result = eval(stmt, env)
 
 		if result != nil {
 			t := result.Type()
@@ -379,27 +1321,87 @@ func evalIdentifier(i *ast.Identifier, env *object.Environment) object.Object {
 		return builtIn
 	}
 
-	return &object.Error{Message: "identifier not found: " + i.Value}
+	return newErrorKind(object.NameErrorKind, "identifier not found: %s", i.Value)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// applyFunction calls fn from the frame caller at the call site pos.
+// An error leaving a Yoru function for the first time records the call
+// stack it was raised in.
+func applyFunction(caller *object.Frame, pos token.Position, fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
 		if len(fn.Parameters) != len(args) {
-			return newError("function requires %d arguments. got=%d", len(fn.Parameters), len(args))
+			return newErrorKind(object.ArgumentErrorKind, "function requires %d arguments. got=%d", len(fn.Parameters), len(args))
+		}
+		frame := &object.Frame{Function: fn.Name, CallPos: pos, Caller: caller, Depth: caller.Depth + 1, Sched: caller.Sched}
+		if frame.Depth > MaxCallDepth {
+			return newErrorKind(object.RecursionErrorKind, "maximum call depth of %d exceeded", MaxCallDepth)
+		}
+		if fn.Generator {
+			return newGenerator(frame, fn, args)
+		}
+		extendedEnv := extendFunctionEnv(fn, args, frame)
+		evaluated := evalBody(fn.Body.Statements, extendedEnv)
+		evaluated = runDeferred(frame, evaluated)
+		if err, ok := evaluated.(*object.Error); ok && err.Trace == nil {
+			err.Trace = frame.Trace()
 		}
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *object.BuiltIn:
+		if overloadableBuiltIns[fn.Name] && len(args) == 1 {
+			if m, ok := operatorMethod(args[0], fn.Name); ok {
+				return applyFunction(caller, pos, m, nil)
+			}
+		}
+		if fn.FrameFn != nil {
+			return fn.FrameFn(caller, args...)
+		}
 		return fn.Fn(args...)
+	case *object.StructType:
+		if len(fn.Fields) != len(args) {
+			return newErrorKind(object.ArgumentErrorKind, "%s requires %d fields (%s). got=%d",
+				fn.Name, len(fn.Fields), strings.Join(fn.Fields, ", "), len(args))
+		}
+		for i, typ := range fn.FieldTypes {
+			if typ != "" && !hasType(args[i], typ) {
+				return newErrorKind(object.TypeErrorKind, "field %s of %s must be %s. got=%s",
+					fn.Fields[i], fn.Name, typ, args[i].Type())
+			}
+		}
+		fields := make([]object.Object, len(args))
+		copy(fields, args)
+		return &object.StructInstance{Struct: fn, Fields: fields}
+	case *object.EnumVariant:
+		if len(fn.Fields) != len(args) {
+			return newErrorKind(object.ArgumentErrorKind, "%s requires %d fields (%s). got=%d",
+				fn.Inspect(), len(fn.Fields), strings.Join(fn.Fields, ", "), len(args))
+		}
+		fields := make([]object.Object, len(args))
+		copy(fields, args)
+		return &object.EnumValue{Variant: fn, Fields: fields}
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newErrorKind(object.TypeErrorKind, "not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+// evalBody runs a function body, converting a Go panic into an error so
+// that the function's deferred calls still run and the call stack is kept.
+// The body of an abandoned generator is left without running them.
+func evalBody(stmts []ast.Statement, env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r == errGeneratorAbandoned {
+				panic(r)
+			}
+			result = panicError(r)
+		}
+	}()
+
+	return evalStatements(stmts, env)
+}
+
+func extendFunctionEnv(fn *object.Function, args []object.Object, frame *object.Frame) *object.Environment {
+	env := object.NewFrameEnvironment(fn.Env, frame)
 
 	for i, v := range fn.Parameters {
 		env.Set(v.Value, args[i])
@@ -420,8 +1422,8 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	var result []object.Object
 
 	for _, e := range exps {
-		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		evaluated := eval(e, env)
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -1,11 +1,15 @@
 package evaluator
 
 import (
+	"runtime"
 	"testing"
+	"time"
 
+	"github.com/yuzuy/yoru/ast"
 	"github.com/yuzuy/yoru/lexer"
 	"github.com/yuzuy/yoru/object"
 	"github.com/yuzuy/yoru/parser"
+	"github.com/yuzuy/yoru/token"
 )
 
 func TestEvalIntegerExpresion(t *testing.T) {
@@ -24,6 +28,29 @@ func TestEvalIntegerExpresion(t *testing.T) {
 		{"-5 * 5", -25},
 		{"-10 / 2", -5},
 		{"5 * (10 + 4)", 70},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 4", 16},
+		{"256 >> 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 << 1 & 7", 5},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"7 ** 0", 1},
+		{"2 ** 62", 4611686018427387904},
+		{"(-2) ** 63", -9223372036854775808},
+		{"2 * 3 ** 2", 18},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 % -1", 0},
+		{"9223372036854775807 - 1 + 1", 9223372036854775807},
+		{"-9223372036854775807 - 1", -9223372036854775808},
+		{"(-9223372036854775807 - 1) % -1", 0},
+		{"3037000499 * 3037000499", 9223372030926249001},
 	}
 
 	for _, tt := range tests {
@@ -32,6 +59,65 @@ func TestEvalIntegerExpresion(t *testing.T) {
 	}
 }
 
+func TestBigIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775808", "9223372036854775808"},
+		{"-9223372036854775809", "-9223372036854775809"},
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"3037000500 * 3037000500", "9223372037000250000"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"2 ** 64", "18446744073709551616"},
+		{"10 ** 30", "1000000000000000000000000000000"},
+		{"1 << 64", "18446744073709551616"},
+		{"-1 << 100", "-1267650600228229401496703205376"},
+		{"(2 ** 100) >> 10", "1237940039285380274899124224"},
+		{"(2 ** 64) * (2 ** 64)", "340282366920938463463374607431768211456"},
+		{"(2 ** 100) / 3", "422550200076076467165567735125"},
+		{"~(2 ** 64)", "-18446744073709551617"},
+		{"(2 ** 64) | 1", "18446744073709551617"},
+		{"(2 ** 64 + 6) ^ 3", "18446744073709551621"},
+		{"99999999999999999999999", "99999999999999999999999"},
+		{`parse_int("123456789012345678901234567890")`, "123456789012345678901234567890"},
+		{"let fact = fn(n) { if (n == 0) { 1 } else { n * fact(n - 1) } }; fact(25)", "15511210043330985984000000"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBigIntObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBigIntegerDemotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"(2 ** 64) - (2 ** 64) + 5", 5},
+		{"(2 ** 64) / (2 ** 60)", 16},
+		{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"-(9223372036854775807 + 1)", -9223372036854775808},
+		{"(2 ** 64) % 10", 6},
+		{"-(2 ** 100) % 7", -2},
+		{"(2 ** 100) >> 90", 1024},
+		{"(2 ** 64) & 255", 0},
+		{"(2 ** 64 + 5) & 255", 5},
+		{"(2 ** 100) >> 1000", 0},
+		{"-(2 ** 100) >> 1000", -1},
+		{"1 ** (2 ** 64)", 1},
+		{"0 << (2 ** 64)", 0},
+		{"len(1..(2 ** 62) * 2 - 1)", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -53,6 +139,12 @@ func TestBooleanExpression(t *testing.T) {
 		{"(1 > 2) == false", true},
 		{"(1 < 2) != true", false},
 		{"(1 > 2) != false", false},
+		{"2 ** 64 == 2 ** 64", true},
+		{"2 ** 64 != 2 ** 64 + 1", true},
+		{"2 ** 64 > 1", true},
+		{"-(2 ** 64) < 1", true},
+		{"2 ** 64 == 1", false},
+		{"2 ** 64 in 1..10", false},
 	}
 
 	for _, tt := range tests {
@@ -171,6 +263,389 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestBlockScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; if (true) { let x = 2; }; x", 1},
+		{"let x = 1; if (true) { let x = 2; x }", 2},
+		{"let x = 1; if (false) { 0 } else { let x = 2; }; x", 1},
+		{"let x = 1; if (true) { x = 2; }; x", 2},
+		{"let x = 1; if (true) { let x = 2; x = 3; }; x", 1},
+		{"let x = 1; if (true) { if (true) { x = 5 } }; x", 5},
+		{"let x = 1; switch 1 { case 1: let x = 2; }; x", 1},
+		{"let x = 1; switch { default: x = 2; }; x", 2},
+		{"let f = if (true) { let y = 5; fn() { y } }; f()", 5},
+		{"let x = 1; let f = fn() { if (true) { let x = 2; } x }; f()", 1},
+		{"let sum = 0; for (i in 1..4) { sum = sum + i }; sum", 10},
+		{"let i = 7; for (i in 1..4) { let i = 0; }; i", 7},
+		{"let a = 0; let b = 0; a = b = 3; a + b", 6},
+		{`
+let counter = fn() {
+	let n = 0;
+	fn() { n = n + 1 }
+};
+let next = counter();
+next();
+next();
+next();
+`, 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedLog string
+		expectedErr string
+	}{
+		{`
+let f = fn() {
+	defer record("a");
+	defer record("b");
+	record("body");
+};
+f();
+`, "[body, b, a]", ""},
+		{`
+let f = fn() {
+	defer record("cleanup");
+	ret 1;
+	record("unreachable");
+};
+f();
+`, "[cleanup]", ""},
+		{`
+let f = fn(x) {
+	defer record(x);
+	x = 2;
+};
+f(1);
+`, "[1]", ""},
+		{`
+let f = fn() {
+	defer record("cleanup");
+	1 + true;
+};
+f();
+`, "[cleanup]", "type mismatch: INTEGER + BOOLEAN"},
+		{`
+let f = fn() {
+	defer fn() { 1 + true }();
+	defer record("cleanup");
+	ret 1;
+};
+f();
+`, "[cleanup]", "type mismatch: INTEGER + BOOLEAN"},
+		{`
+let f = fn() {
+	defer fn() { 1 + "a" }();
+	1 + true;
+};
+f();
+`, "[]", "type mismatch: INTEGER + BOOLEAN"},
+		{`
+let inner = fn() { defer record("inner"); record("in") };
+let outer = fn() { defer record("outer"); inner(); record("out") };
+outer();
+`, "[in, inner, out, outer]", ""},
+		{`
+defer record("program");
+if (true) { defer record("block"); }
+record("top");
+`, "[top, block, program]", ""},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("log", &object.Array{})
+		testEvalWithEnv(`let record = fn(x) { log = push(log, x) };`, env)
+
+		evaluated := testEvalWithEnv(tt.input, env)
+		if tt.expectedErr != "" {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			} else if errObj.Message != tt.expectedErr {
+				t.Errorf("wrong error message. expected=%s, got=%s", tt.expectedErr, errObj.Message)
+			}
+		} else if isError(evaluated) {
+			t.Errorf("unexpected error: %s", evaluated.Inspect())
+		}
+
+		log, _ := env.Get("log")
+		if log.Inspect() != tt.expectedLog {
+			t.Errorf("wrong log. expected=%s, got=%s", tt.expectedLog, log.Inspect())
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { 1 + true } catch (e) { 2 }`, 2},
+		{`try { throw "bad" } catch (e) { e["message"] }`, "bad"},
+		{`try { throw "bad" } catch (e) { e["kind"] }`, "Error"},
+		{`try { throw 42 } catch (e) { e["value"] }`, 42},
+		{`try { 1 + true } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { hoge } catch (e) { e["kind"] }`, "NameError"},
+		{`try { len(1, 2) } catch (e) { e["kind"] }`, "ArgumentError"},
+		{`try { throw error("no such user", "LookupError") } catch (e) { e["kind"] }`, "LookupError"},
+		{`try { round(1.5d, 16000000) } catch (e) { e["kind"] }`, "ArgumentError"},
+		{`try {
+  1;
+  1 + true
+} catch (e) { e["line"] }`, 3},
+		{`try {
+  1;
+  1 + true
+} catch (e) { e["column"] }`, 5},
+		{`try { throw "a" } catch { 2 }`, 2},
+		{`let f = fn() { throw "deep" }; let g = fn() { f() }; try { g() } catch (e) { e["message"] }`, "deep"},
+		{`try { try { throw "inner" } catch (e) { throw e } } catch (e) { e["message"] }`, "inner"},
+		{`try { try { throw "inner" } finally { 1 } } catch (e) { e["message"] }`, "inner"},
+		{`let x = 0; try { 1 } finally { x = 5 }; x`, 5},
+		{`let x = 0; try { throw 1 } catch { x = 1 } finally { x = x + 1 }; x`, 2},
+		{`let f = fn() { try { ret 1 } finally { ret 2 } }; f()`, 2},
+		{`let f = fn() { try { ret 1 } catch { ret 2 } }; f()`, 1},
+		{`let e = 5; try { throw 1 } catch (e) { 0 }; e`, 5},
+		{`try { throw "bad" } catch (e) { e.message }`, "bad"},
+		{`try { 1 + true } catch (e) { e.kind }`, "TypeError"},
+		{`try { throw 42 } catch (e) { e.value }`, 42},
+		{`try {
+  1 + true
+} catch (e) { e.line * 10 + e.column }`, 25},
+		{`try { try { throw "x" } catch (e) { e.nope } } catch (e) { e.message }`, "ERROR_VALUE has no field or method nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object not *object.String. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		}
+	}
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`is_error(error("bad"))`, true},
+		{`is_error(1)`, false},
+		{`is_error(parse_int("x"))`, true},
+		{`is_error(parse_int(" 12 "))`, false},
+		{`parse_int("12") + 1`, 13},
+		{`let e = parse_int("x"); e["kind"]`, "ValueError"},
+		{`let e = parse_int("x"); e["message"]`, "invalid integer: \"x\""},
+		{`let f = fn(s) { parse_int(s)? + 1 }; f("41")`, 42},
+		{`let f = fn(s) { let n = parse_int(s)?; n * 2 }; is_error(f("x"))`, true},
+		{`let f = fn(s) { let n = parse_int(s)?; ret n * 2 }; f("x")["message"]`, "invalid integer: \"x\""},
+		{`let f = fn(s) { [parse_int(s)?, 2][0] }; is_error(f("x"))`, true},
+		{`let f = fn(s) { for (x in 1..3) { parse_int(s)? }; 1 }; is_error(f("x"))`, true},
+		{`let f = fn(s) { ret parse_int(s)?; }; is_error(f("x"))`, true},
+		{`let f = fn(s) { let x = 0; defer fn() { x = 1 }(); parse_int(s)? }; is_error(f("x"))`, true},
+		{`let g = fn(s) { parse_int(s)? }; let h = fn(s) { g(s)? + 100 }; h("1")`, 101},
+		{`let g = fn(s) { parse_int(s)? }; let h = fn(s) { g(s)? + 100 }; is_error(h("x"))`, true},
+		{`5?`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object not *object.String. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		}
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	input := `let add = fn(a, b) {
+	a + b
+};
+let compute = fn(x) {
+	add(x, true)
+};
+compute(1);`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Pos != (token.Position{Line: 2, Column: 4}) {
+		t.Errorf("wrong error position. got=%s", errObj.Pos)
+	}
+
+	expected := []object.TraceEntry{
+		{Function: "add", Pos: token.Position{Line: 5, Column: 5}},
+		{Function: "compute", Pos: token.Position{Line: 7, Column: 8}},
+	}
+	if len(errObj.Trace) != len(expected) {
+		t.Fatalf("wrong trace length. expected=%d, got=%d", len(expected), len(errObj.Trace))
+	}
+	for i, e := range expected {
+		if errObj.Trace[i] != e {
+			t.Errorf("trace[%d] wrong. expected=%+v, got=%+v", i, e, errObj.Trace[i])
+		}
+	}
+}
+
+func TestStackTraceInCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn() { throw "x" }; try { f() } catch (e) { len(e["trace"]) }`, 1},
+		{`let f = fn() { throw "x" }; try { f() } catch (e) { e["trace"][0]["function"] }`, "f"},
+		{`let f = fn() { throw "x" }; let g = fn() { f() }; try { g() } catch (e) { e["trace"][1]["function"] }`, "g"},
+		{`let f = fn() { throw "x" };
+try { f() } catch (e) { e["trace"][0]["line"] }`, 2},
+		{`try { fn() { throw "x" }() } catch (e) { e["trace"][0]["function"] }`, ""},
+		{`try { throw "x" } catch (e) { len(e["trace"]) }`, 0},
+		{`let f = fn() { throw "x" }; let g = fn(e) { throw e };
+try { try { f() } catch (e) { g(e) } } catch (e) { e["trace"][0]["function"] }`, "g"},
+		{`let saved = 0; try { throw "x" } catch (e) { saved = e };
+try { throw saved } catch (e) { e["line"] }`, 2},
+		{`let saved = 0; try { throw "x" } catch (e) { saved = e };
+try { throw saved } catch (e) { 0 }; saved["line"]`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object not *object.String. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		}
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedLog string
+	}{
+		{`boom()`, "[]"},
+		{`defer record("cleanup"); boom(); record("unreachable")`, "[cleanup]"},
+		{`let f = fn() { defer record("cleanup"); boom(); record("unreachable") }; f()`, "[cleanup]"},
+		{`let f = fn() { boom() }; try { f() } catch (e) { record(e["kind"]) }`, "[InternalError]"},
+		{`try { 1 / 0 } catch (e) { record(e["kind"]) }`, "[ArithmeticError]"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("log", &object.Array{})
+		env.Set("boom", &object.BuiltIn{Fn: func(args ...object.Object) object.Object {
+			panic("boom")
+		}})
+		testEvalWithEnv(`let record = fn(x) { log = push(log, x) };`, env)
+
+		evaluated := testEvalWithEnv(tt.input, env)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Kind != object.InternalErrorKind {
+				t.Errorf("wrong error kind. expected=%s, got=%s", object.InternalErrorKind, errObj.Kind)
+			}
+			if errObj.Message != "internal error: boom" {
+				t.Errorf("wrong error message. got=%q", errObj.Message)
+			}
+		}
+
+		log, _ := env.Get("log")
+		if log.Inspect() != tt.expectedLog {
+			t.Errorf("wrong log. expected=%s, got=%s", tt.expectedLog, log.Inspect())
+		}
+	}
+}
+
+func TestPanicRecoveryFromMalformedAST(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Expression: (*ast.FunctionLiteral)(nil)},
+		},
+	}
+
+	evaluated := Eval(program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.InternalErrorKind {
+		t.Errorf("wrong error kind. expected=%s, got=%s", object.InternalErrorKind, errObj.Kind)
+	}
+	if errObj.GoStack != "" {
+		t.Errorf("Go stack recorded outside of debug mode")
+	}
+
+	Debug = true
+	defer func() { Debug = false }()
+
+	errObj = Eval(program, object.NewEnvironment()).(*object.Error)
+	if errObj.GoStack == "" {
+		t.Errorf("Go stack not recorded in debug mode")
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	defer func(depth int) { MaxCallDepth = depth }(MaxCallDepth)
+	MaxCallDepth = 100
+
+	input := `let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } };`
+
+	testIntegerObject(t, testEval(input+"f(99)"), 0)
+
+	evaluated := testEval(input + "f(100)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.RecursionErrorKind {
+		t.Errorf("wrong error kind. expected=%s, got=%s", object.RecursionErrorKind, errObj.Kind)
+	}
+	if len(errObj.Trace) != 100 {
+		t.Errorf("wrong trace length. expected=100, got=%d", len(errObj.Trace))
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -206,6 +681,13 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y) { x + y; }; add(5, 5)", 10},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5))", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"let double = x => x * 2; double(5)", 10},
+		{"let add = (x, y) => x + y; add(5, 5)", 10},
+		{"let add = fn(x: int, y: int) -> int { x + y }; let n: int = add(5, 5); n", 10},
+		{"let five = () => 5; five()", 5},
+		{"let add = (x, y) => { let z = x + y; ret z; }; add(2, 3)", 5},
+		{"let apply = fn(f, v) { f(v) }; apply(x => x + 1, 4)", 5},
+		{"(x => x * x)(3)", 9},
 	}
 
 	for _, tt := range tests {
@@ -248,6 +730,10 @@ func TestStringComparison(t *testing.T) {
 	}{
 		{`"hoge" == "hoge"`, true},
 		{`"hoge" != "hoge"`, false},
+		{`"abc" < "abd"`, true},
+		{`"ab" < "abc"`, true},
+		{`"b" > "abc"`, true},
+		{`"" > ""`, false},
 	}
 
 	for _, v := range tests {
@@ -256,6 +742,118 @@ func TestStringComparison(t *testing.T) {
 	}
 }
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[[1, [2]], 3] == [[1, [2]], 3]", true},
+		{"[1, 2.0d] == [1, 2]", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{"{} == {}", true},
+		{"[] == {}", false},
+		{"null == null", true},
+		{`[null, true, "x"] == [null, true, "x"]`, true},
+		{"1..5 == 1..5", true},
+		{"1..5 == 1..<5", false},
+		{"let f = fn() {}; f == f", true},
+		{"fn() {} == fn() {}", false},
+		{"(2 ** 64) == (2 ** 64)", true},
+		{`1 == "1"`, false},
+		{`error("x") == error("x")`, true},
+		{"[1, 2] < [1, 3]", true},
+		{"[1, 2] < [1, 2, 0]", true},
+		{"[2] > [1, 9]", true},
+		{`["b"] > ["a", "z"]`, true},
+		{"[] < []", false},
+		{"[[1, 2], 3] < [[1, 3]]", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestSequenceOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"-" * 5`, "-----"},
+		{`3 * "ab"`, "ababab"},
+		{`"abc" * 0`, ""},
+		{"[1, 2] + [3]", []int64{1, 2, 3}},
+		{"[] + []", []int64{}},
+		{"[1, 2] * 2", []int64{1, 2, 1, 2}},
+		{"2 * [0]", []int64{0, 0}},
+		{"[1] * 0", []int64{}},
+		{"[] * 9223372036854775807", []int64{}},
+		{"let a = [1]; let b = a + [2]; a", []int64{1}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. expected=%d, got=%d", len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testIntegerObject(t, arr.Elements[i], el)
+			}
+		}
+	}
+}
+
+func TestMembership(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"2 in [1, 2, 3]", true},
+		{"4 in [1, 2, 3]", false},
+		{"[1] in [[1], [2]]", true},
+		{`"a" in ["a", 1]`, true},
+		{"1.0d in [1]", true},
+		{"1 in []", false},
+		{`"k" in {"k": 1}`, true},
+		{`"v" in {"k": "v"}`, false},
+		{`"ell" in "hello"`, true},
+		{`"" in "hello"`, true},
+		{`"xyz" in "hello"`, false},
+		{"3 in 1..5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2, 3 * 4]"
 
@@ -309,6 +907,10 @@ let two = "two"
 	"one":   1,
 	two:     2,
 	"three": 3,
+	4:       4,
+	true:    5,
+	false:   6,
+	null:    7,
 }
 `
 
@@ -318,23 +920,559 @@ let two = "two"
 		t.Fatalf("Eval not returned Hash. got=%T(%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]int64{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{True, 5},
+		{False, 6},
+		{Null, 7},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
-	for expectedK, expectedV := range expected {
-		p, ok := result.Pairs[expectedK]
+	for _, tt := range expected {
+		v, ok := result.Get(tt.key)
 		if !ok {
-			t.Errorf("no pair for key %q found", expectedK)
+			t.Errorf("no pair for key %s found", tt.key.Inspect())
+			continue
 		}
 
-		testIntegerObject(t, p.Value, expectedV)
+		testIntegerObject(t, v, tt.value)
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`{3: "x", 1: "y", 2: "z"}`, "{3: x, 1: y, 2: z}"},
+		{`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+		{`keys({"b": 1, "a": 2, "c": 3})`, "[b, a, c]"},
+		{`values({"b": 1, "a": 2, "c": 3})`, "[1, 2, 3]"},
+		{`let out = []; for (k in {"z": 1, "y": 2, "x": 3}) { out = push(out, k) }; out`, "[z, y, x]"},
+		{`keys({})`, "[]"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestSetExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#{}", "#{}"},
+		{"#{1, 2, 3}", "#{1, 2, 3}"},
+		{"#{3, 1, 3, 2, 1}", "#{3, 1, 2}"},
+		{`#{1, "1", true, null}`, "#{1, 1, true, null}"},
+		{"#{1, 2} | #{2, 3}", "#{1, 2, 3}"},
+		{"#{1, 2, 3} & #{3, 2, 4}", "#{2, 3}"},
+		{"#{1, 2, 3} - #{2}", "#{1, 3}"},
+		{"#{1, 2, 3} ^ #{3, 4}", "#{1, 2, 4}"},
+		{"add(#{1}, 2, 1, 3)", "#{1, 2, 3}"},
+		{"remove(#{1, 2, 3}, 2, 4)", "#{1, 3}"},
+		{"let s = #{1}; add(s, 2); s", "#{1}"},
+		{"len(#{1, 2, 2})", "2"},
+		{"2 in #{1, 2}", "true"},
+		{`"2" in #{1, 2}`, "false"},
+		{"#{1, 2} == #{2, 1}", "true"},
+		{"#{1, 2} != #{1}", "true"},
+		{"#{1} == [1]", "false"},
+		{"let sum = 0; for (x in #{1, 2, 3}) { sum = sum + x }; sum", "6"},
+		{"to_json(#{1, 2})", "[1,2]"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestTupleExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"()", "()"},
+		{"(1,)", "(1,)"},
+		{`(1, "a", [2])`, "(1, a, [2])"},
+		{`tuple(1, "a")`, "(1, a)"},
+		{"tuple()", "()"},
+		{"(1, 2)[1]", "2"},
+		{"(1, 2)[2]", "null"},
+		{"len((1, 2, 3))", "3"},
+		{"let (a, b) = (1, 2); a + b", "3"},
+		{"let (q, r) = fn(a, b) { (a / b, a % b) }(7, 2); [q, r]", "[3, 1]"},
+		{"let (x, y) = [1, 2]; y", "2"},
+		{"(1, 2) == (1, 2)", "true"},
+		{"(1, 2) == [1, 2]", "false"},
+		{"(1, (2, 3)) == (1, (2, 3))", "true"},
+		{"(1, 2) < (1, 3)", "true"},
+		{`(2, "a") > (1, "z")`, "true"},
+		{"(1,) < (1, 0)", "true"},
+		{`{(1, 2): "a"}[(1, 2)]`, "a"},
+		{`{(1, 2): "a"}[(2, 1)]`, "null"},
+		{`{(1, [2]): "a"}[(1, [2])]`, "a"},
+		{"#{(1, 2), (1, 2), (2, 1)}", "#{(1, 2), (2, 1)}"},
+		{"(1,) == (1.0d,)", "true"},
+		{"len(#{(1,), (1.0d,)})", "1"},
+		{`{(1,): "a"}[(1.0d,)]`, "a"},
+		{`{(1.5d, 2 ** 64): "a"}[(1.50d, 2d ** 64)]`, "a"},
+		{"2 in (1, 2)", "true"},
+		{"let sum = 0; for (x in (1, 2, 3)) { sum = sum + x }; sum", "6"},
+		{"to_json((1, (2, 3)))", "[1,[2,3]]"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	point := `
+struct Point {
+	x, y
+	fn add(other) { Point(self.x + other.x, self.y + other.y) }
+	fn norm() { self.x * self.x + self.y * self.y }
+	fn scale(k) { Point(self.x * k, self.y * k) }
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Point", "struct Point { x, y }"},
+		{"Point(1, 2)", "Point(x: 1, y: 2)"},
+		{"Point(1, 2).x", "1"},
+		{"let p = Point(3, 4); p.y", "4"},
+		{"Point(3, 4).norm()", "25"},
+		{"Point(1, 2).add(Point(10, 20))", "Point(x: 11, y: 22)"},
+		{"Point(1, 2).scale(3).add(Point(1, 1)).x", "4"},
+		{"let f = Point(1, 2).norm; f()", "5"},
+		{"type(Point(1, 2))", "Point"},
+		{"type(Point)", "STRUCT"},
+		{"type(1)", "INTEGER"},
+		{"Point(1, 2) == Point(1, 2)", "true"},
+		{"Point(1, 2) == Point(2, 1)", "false"},
+		{"struct Other { x, y }; Point(1, 2) == Other(1, 2)", "false"},
+		{"to_json(Point(1, [2]))", `{"x":1,"y":[2]}`},
+		{"if (true) { struct Point { z } }; Point(1, 2).x", "1"},
+		{"struct Line { from: Point, to: Point }; Line(Point(0, 0), Point(1, 2)).to.y", "2"},
+		{"struct Entry { name: string, amount: decimal, tag: any }; Entry(\"a\", 1.50d, null).amount", "1.50"},
+		{"struct Big { n: int, f: fn }; Big(2 ** 70, Point).n", "1180591620717411303424"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(point+tt.input), tt.expected)
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	point := "struct Point { x, y; fn norm() { self.x * self.x + self.z } }\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Point(1)", "Point requires 2 fields (x, y). got=1"},
+		{"Point(1, 2, 3)", "Point requires 2 fields (x, y). got=3"},
+		{"Point(1, 2).z", "Point has no field or method z"},
+		{"Point(1, 2).norm()", "Point has no field or method z"},
+		{"Point(1, 2) + 1", "type mismatch: Point + INTEGER"},
+		{"Point(1, 2) + Point(1, 2)", "unknown operator: Point + Point"},
+		{"let h = {}; h.x", "HASH has no field or method x"},
+		{"struct STRING { s }; STRING(\"x\") + STRING(\"x\")", "struct name STRING is reserved for a built-in type"},
+		{"enum INTEGER { A(s) }; A(\"x\") + 1", "enum name INTEGER is reserved for a built-in type"},
+		{"struct string { v }; struct Box { s: string }; Box(string(1))", "struct name string is reserved for a built-in type"},
+		{"enum any { A }; A", "enum name any is reserved for a built-in type"},
+		{"struct Entry { name: string, amount: decimal }; Entry(\"a\", 1)", "field amount of Entry must be decimal. got=INTEGER"},
+		{"struct Line { from: Point, to: Point }; Line(Point(0, 0), 3)", "field to of Line must be Point. got=INTEGER"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, tt.input, testEval(point+tt.input), tt.expected)
+	}
+
+	evaluated := testEval(point + "Point(1, 2).norm()")
+	errObj := evaluated.(*object.Error)
+	if len(errObj.Trace) == 0 || errObj.Trace[0].Function != "Point.norm" {
+		t.Errorf("method missing from stack trace. got=%+v", errObj.Trace)
+	}
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	money := `
+struct Money {
+	cents
+	fn +(other) { Money(self.cents + other.cents) }
+	fn *(k) { Money(self.cents * k) }
+	fn ==(other) { self.cents == other.cents }
+	fn <(other) { self.cents < other.cents }
+	fn [](i) { [self.cents / 100, self.cents % 100][i] }
+	fn len() { 2 }
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Money(150) + Money(275)", "Money(cents: 425)"},
+		{"Money(150) * 3", "Money(cents: 450)"},
+		{"Money(150) + Money(1) * 2", "Money(cents: 152)"},
+		{"Money(150) == Money(150)", "true"},
+		{"Money(150) != Money(150)", "false"},
+		{"Money(150) != Money(1)", "true"},
+		{"Money(1) < Money(2)", "true"},
+		{"Money(1) > Money(2)", "false"},
+		{"Money(3) > Money(2)", "true"},
+		{"Money(425)[0]", "4"},
+		{"Money(425)[1]", "25"},
+		{"len(Money(425))", "2"},
+		{"len([Money(1)])", "1"},
+		{`switch (Money(5)) { case Money(5): "five" default: "other" }`, "five"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(money+tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"Money(1) - Money(1)", "unknown operator: Money - Money"},
+		{"2 * Money(1)", "type mismatch: INTEGER * Money"},
+		{"struct Bad { fn +(a, b) { a } }; Bad() + 1", "function requires 2 arguments. got=1"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, tt.input, testEval(money+tt.input), tt.expected)
+	}
+
+	evaluated := testEval("struct R { fn len() { len(self) } }; len(R())")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != object.RecursionErrorKind {
+		t.Errorf("recursive len method not stopped. got=%v", evaluated)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	lib := `
+let naturals = fn() { for (i in 0..(2 ** 62)) { yield i; } };
+let take = fn(g, n) { for (i in 1..n) { yield next(g); } };
+let map = fn(g, f) { for (x in g) { yield f(x); } };
+let collect = fn(g) { let out = []; for (x in g) { out = push(out, x); }; out };
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"collect(take(naturals(), 5))", "[0, 1, 2, 3, 4]"},
+		{"collect(take(map(naturals(), x => x * x), 4))", "[0, 1, 4, 9]"},
+		{"let g = naturals(); next(g); next(g); next(g)", "2"},
+		{"let g = take(naturals(), 1); next(g); next(g)", "null"},
+		{"let g = fn() { yield 1; ret 5; yield 2; }; collect(g())", "[1]"},
+		{"let g = fn() { if (false) { yield 1; } }; collect(g())", "[]"},
+		{"let g = fn() { yield 1 }; let a = g(); let b = g(); next(a); next(a); next(b)", "1"},
+		{"let log = []; let g = fn() { defer fn() { log = push(log, \"done\") }(); yield 1; }; collect(g()); log", "[done]"},
+		{"let g = fn() { yield 1; }; let a = g(); for (x in a) {}; collect(a)", "[]"},
+		{"struct R { n; fn each() { for (i in 1..self.n) { yield i; } } }; collect(R(3).each())", "[1, 2, 3]"},
+		{"naturals()", "generator naturals"},
+		{"type(naturals())", "GENERATOR"},
+		{"let f = fn() { for (x in naturals()) { if (x == 3) { ret x } } }; f()", "3"},
+		{`let log = []; let g = fn() { defer fn() { log = push(log, "defer") }(); try { yield 1; yield 2; } finally { log = push(log, "finally") } };
+let a = g(); next(a); close(a); [log, next(a)]`, "[[finally, defer], null]"},
+		{"let g = fn() { for (i in 0..10) { try { yield i; } catch (e) {} } }; let a = g(); next(a); close(a); next(a)", "null"},
+		{"let g = fn() { try { yield 1; } finally { yield 2; } }; let a = g(); next(a); close(a); next(a)", "null"},
+		{"let a = naturals(); close(a); close(a); next(a)", "null"},
+		{"let a = naturals(); next(a); close(a)", "null"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(lib+tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let g = fn() { yield 1; throw \"boom\"; }; collect(g())", "boom"},
+		{"let g = fn() { yield 1; throw \"boom\"; }; let a = g(); next(a); next(a)", "boom"},
+		{"let g = fn(a) { yield a; }; g()", "function requires 1 arguments. got=0"},
+		{"let g = fn() { yield next(a); }; let a = g(); next(a)", "generator already running"},
+		{"next([1])", "argument to `next` must be GENERATOR. got=ARRAY"},
+		{"let g = fn() { try { yield 1; } finally { throw \"cleanup\" } }; let a = g(); next(a); close(a)", "cleanup"},
+		{"let g = fn() { close(a); yield 1; }; let a = g(); next(a)", "generator already running"},
+		{"close(1)", "argument to `close` must be CHANNEL or GENERATOR. got=INTEGER"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, tt.input, testEval(lib+tt.input), tt.expected)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	lib := `
+let produce = fn(ch, n) { for (i in 1..n) { send(ch, i); }; close(ch); };
+let collect = fn(ch) { let out = []; for (x in ch) { out = push(out, x); }; out };
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let t = spawn fn(x) { x * 2 }(21); wait(t)", "42"},
+		{"let sq = fn(x) { x * x }; wait(spawn sq(2), spawn sq(3))", "[4, 9]"},
+		{"let sq = fn(x) { x * x }; let ts = []; for (i in 1..4) { ts = push(ts, spawn sq(i)); }; wait(ts)", "[1, 4, 9, 16]"},
+		{"let ch = channel(); spawn produce(ch, 3); collect(ch)", "[1, 2, 3]"},
+		{"let ch = channel(2); send(ch, 1); send(ch, 2); close(ch); [receive(ch), receive(ch), receive(ch)]", "[1, 2, null]"},
+		{"let n = 0; let ch = channel(); let inc = fn() { n = n + 1; send(ch, 1) }; for (i in 1..50) { spawn inc(); receive(ch); }; n", "50"},
+		{"let ch = channel(1); send(ch, \"a\"); select { case v = receive(ch): v default: \"none\" }", "a"},
+		{"let ch = channel(); select { case v = receive(ch): v default: \"none\" }", "none"},
+		{"let ch = channel(1); select { case send(ch, 5): receive(ch) }", "5"},
+		{"let ch = channel(); close(ch); select { case v = receive(ch): v }", "null"},
+		{"let a = channel(); let b = channel(); spawn send(b, 2); select { case x = receive(a): x case y = receive(b): y * 10 }", "20"},
+		{"let t = spawn sleep(1); wait(t)", "null"},
+		{"wait(spawn len([1, 2]))", "2"},
+		{"let f = fn() {}; wait(spawn f())", "null"},
+		{"spawn len([])", "task len"},
+		{"channel()", "channel"},
+		{"[type(channel()), type(spawn len([]))]", "[CHANNEL, TASK]"},
+		{"try { receive(channel()) } catch (e) { e[\"kind\"] }", "DeadlockError"},
+		{"let ch = channel(1); try { receive(ch) } catch (e) { send(ch, 2) }; receive(ch)", "2"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(lib+tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"wait(spawn fn() { throw \"boom\" }())", "boom"},
+		{"let f = fn() { 1 }; wait(spawn f(), 2)", "argument to `wait` must be TASK. got=INTEGER"},
+		{"let ch = channel(); close(ch); send(ch, 1)", "send on closed channel"},
+		{"let ch = channel(); close(ch); close(ch)", "close of closed channel"},
+		{"let ch = channel(); close(ch); select { case send(ch, 1): 1 }", "send on closed channel"},
+		{"select { case receive(1): 1 }", "select case requires CHANNEL. got=INTEGER"},
+		{"channel(-1)", "invalid channel size: -1"},
+		{"receive([1])", "argument to `receive` must be CHANNEL. got=ARRAY"},
+		{"spawn 5()", "not a function: INTEGER"},
+		{"receive(channel())", "deadlock: all tasks are blocked"},
+		{"send(channel(), 1)", "deadlock: all tasks are blocked"},
+		{"let ch = channel(1); send(ch, 1); send(ch, 2)", "deadlock: all tasks are blocked"},
+		{"select { case receive(channel()): 1 }", "deadlock: all tasks are blocked"},
+		{"select {}", "deadlock: all tasks are blocked"},
+		{"for (x in channel()) {}", "deadlock: all tasks are blocked"},
+		{"let ch = channel(); wait(spawn receive(ch))", "deadlock: all tasks are blocked"},
+		{"let a = channel(); let b = channel(); spawn fn() { receive(a); send(b, 1) }(); receive(b)", "deadlock: all tasks are blocked"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, tt.input, testEval(lib+tt.input), tt.expected)
+	}
+}
+
+func TestGeneratorCleanup(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// the generator is reachable from its own body, so only closing it
+	// ends the body
+	input := `let n = 0;
+let f = fn() {
+	let count = fn() { defer fn() { n = n + 1 }(); yield 1; yield 2; };
+	let g = count();
+	next(g);
+	close(g)
+};
+for (i in 1..1000) { f() };
+n`
+	testIntegerObject(t, testEval(input), 1000)
+
+	// a for loop left early closes its generator right away
+	input = `let log = [];
+let g = fn() { defer fn() { log = push(log, "closed") }(); yield 1; yield 2; };
+let first = fn() { for (x in g()) { ret x } };
+[first(), log]`
+	testInspectObject(t, input, testEval(input), "[1, [closed]]")
+	input = `let log = [];
+let g = fn() { defer fn() { log = push(log, "closed") }(); yield 1; yield 2; };
+try { for (x in g()) { throw "boom" } } catch (e) { log }`
+	testInspectObject(t, input, testEval(input), "[closed]")
+
+	// the generators still suspended are closed when Eval returns
+	env := object.NewEnvironment()
+	input = `let log = [];
+let gen = fn() { defer fn() { log = push(log, "closed") }(); yield 1; yield 2 };
+let it = gen();
+next(it)`
+	testIntegerObject(t, testEvalWithEnv(input, env), 1)
+	if log, _ := env.Get("log"); log.Inspect() != "[closed]" {
+		t.Errorf("suspended generator not closed. got=%s", log.Inspect())
+	}
+	testEvalWithEnv("let g = fn() { yield 1; yield 2 }; for (i in 1..100) { next(g()) }", env)
+
+	waitForGoroutines(t, before)
+}
+
+func TestTaskCancellation(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// the tasks still running when Eval returns are cancelled, running
+	// their cleanup
+	env := object.NewEnvironment()
+	input := `let log = [];
+let ch = channel();
+spawn fn() { defer fn() { log = push(log, "receive") }(); receive(ch) }();
+spawn fn() { defer fn() { log = push(log, "sleep") }(); sleep(100000) }();
+spawn fn() { defer fn() { log = push(log, "loop") }(); for (i in 1..1000000000000) {} }();
+let t = spawn receive(ch);
+1`
+	start := time.Now()
+	testIntegerObject(t, testEvalWithEnv(input, env), 1)
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("Eval waited for its tasks. took=%s", d)
+	}
+	if log, _ := env.Get("log"); len(log.(*object.Array).Elements) != 3 {
+		t.Errorf("cancelled tasks did not run their cleanup. got=%s", log.Inspect())
+	}
+	waitForGoroutines(t, before)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { wait(t) } catch (e) { e.kind }", "CancelledError"},
+		// a later Eval in the environment has its own tasks
+		{"let c = channel(); spawn send(c, 2); receive(c)", "2"},
+	}
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEvalWithEnv(tt.input, env), tt.expected)
+	}
+	testErrorObject(t, "wait(t)", testEvalWithEnv("wait(t)", env), "task cancelled: the program has ended")
+
+	// environments do not share channels, or blocked tasks when checking
+	// for deadlock
+	other := object.NewEnvironment()
+	done := make(chan object.Object)
+	go func() {
+		done <- testEvalWithEnv("let c = channel(); spawn fn() { sleep(50); send(c, 3) }(); receive(c)", other)
+	}()
+	evaluated := testEvalWithEnv("let c = channel(); receive(c)", env)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Kind != object.DeadlockErrorKind {
+		t.Errorf("deadlock not detected. got=%v", evaluated)
+	}
+	testIntegerObject(t, <-done, 3)
+
+	c, _ := other.Get("c")
+	env.Set("c", c)
+	testErrorObject(t, "receive(c)", testEvalWithEnv("receive(c)", env), "channel or task belongs to another environment")
+}
+
+// waitForGoroutines fails t unless the goroutines started since there
+// were before end shortly, without waiting for the garbage collector.
+func waitForGoroutines(t *testing.T, before int) {
+	t.Helper()
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("goroutines left running. before=%d, after=%d", before, n)
+	}
+}
+
+func TestEnums(t *testing.T) {
+	shape := `
+enum Shape { Circle(r), Rect(w, h), Empty }
+let area = fn(s) {
+	switch s {
+	case Circle(r):
+		3 * r * r
+	case Shape.Rect(w, h):
+		w * h
+	case Empty:
+		0
+	}
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Shape", "enum Shape { Circle(r), Rect(w, h), Empty }"},
+		{"Circle", "Shape.Circle"},
+		{"Circle(2)", "Shape.Circle(r: 2)"},
+		{"Shape.Rect(2, 3)", "Shape.Rect(w: 2, h: 3)"},
+		{"Empty", "Shape.Empty"},
+		{"Shape.Empty", "Shape.Empty"},
+		{"Rect(2, 3).h", "3"},
+		{"type(Circle(1))", "Shape"},
+		{"type(Shape)", "ENUM"},
+		{"area(Circle(2))", "12"},
+		{"area(Rect(2, 5))", "10"},
+		{"area(Empty)", "0"},
+		{"Circle(1) == Circle(1)", "true"},
+		{"Circle(1) == Circle(2)", "false"},
+		{"Empty == Shape.Empty", "true"},
+		{`{Empty: "e", Circle(1): "c"}[Circle(1)]`, "c"},
+		{"switch Rect(1, 2) { case Rect(_, h): h }", "2"},
+		{"switch Rect(1, 2) { case Circle: 1 default: 2 }", "2"},
+		{"switch Circle(4) { case Circle: 1 default: 2 }", "1"},
+		{"switch 5 { case Circle(r): r default: 0 }", "0"},
+		{"let r = 1; switch Circle(9) { case Circle(r): r }; r", "1"},
+	}
+
+	for _, tt := range tests {
+		testInspectObject(t, tt.input, testEval(shape+tt.input), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"Circle(1, 2)", "Shape.Circle requires 1 fields (r). got=2"},
+		{"Shape.Square", "Shape has no variant Square"},
+		{"Circle(1).w", "Shape has no field or method w"},
+		{"switch Circle(1) { case Circle(a, b): 1 }", "pattern for Shape.Circle requires 1 fields (r). got=2"},
+		{"switch Circle(1) { case Circle(1): 1 }", "pattern for Shape.Circle must bind names. got 1"},
+	}
+
+	for _, tt := range errorTests {
+		testErrorObject(t, tt.input, testEval(shape+tt.input), tt.expected)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`to_json({"b": 1, "a": [true, null, "x"]})`, `{"b":1,"a":[true,null,"x"]}`},
+		{`to_json({1: 12.50d, true: 2 ** 64, null: {}})`, `{"1":12.50,"true":18446744073709551616,"null":{}}`},
+		{`to_json("say \"hi\"\n\t")`, `"say \"hi\"\n\t"`},
+		{`to_json([])`, `[]`},
+		{`to_json(-3)`, `-3`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong JSON. expected=%q, got=%q", tt.expected, str.Value)
+		}
 	}
 }
 
@@ -347,6 +1485,96 @@ func TestHashIndexLiterals(t *testing.T) {
 		{`{"foo": 5}["bar"]`, nil},
 		{`let foo = "foo"; {"foo": 5}[foo]`, 5},
 		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{5: 5}["5"]`, nil},
+		{`{"5": 5}[5]`, nil},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{null: 5}[null]`, 5},
+		{`{1: 1, true: 2, "1": 3}[true]`, 2},
+		{`{2 ** 64: 5}[2 ** 64]`, 5},
+		{`{-1: 5}[0 - 1]`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"len(1..10)", 10},
+		{"len(0..<10)", 10},
+		{"len(0..<0)", 0},
+		{"len(10..1)", 0},
+		{"len(0..10 step 3)", 4},
+		{"len(10..0 step -2)", 6},
+		{"len(10..<0 step -2)", 5},
+		{"(1..10)[0]", 1},
+		{"(1..10)[9]", 10},
+		{"(1..10)[10]", nil},
+		{"(1..10)[-1]", nil},
+		{"(0..20 step 5)[3]", 15},
+		{"(10..0 step -3)[2]", 4},
+		{"let n = 3; (0..<n)[n - 1]", 2},
+		{"len(0..<9223372036854775807)", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestRangeMembership(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"5 in 1..10", true},
+		{"10 in 1..10", true},
+		{"10 in 1..<10", false},
+		{"0 in 1..10", false},
+		{"4 in 0..10 step 2", true},
+		{"5 in 0..10 step 2", false},
+		{"4 in 10..0 step -3", true},
+		{"1 in 10..0 step -3", true},
+		{"2 in 10..0 step -3", false},
+		{"1 in 1..<1", false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"for (x in [1, 2, 3]) { if (x == 2) { ret x } }", 2},
+		{"for (x in [1, 2, 3]) { x }", nil},
+		{"for (x in 1..1000000000000) { if (x * x > 50) { ret x } }", 8},
+		{"for (x in 0..<0) { ret 1 }", nil},
+		{"let f = fn() { for (x in 5..1 step -1) { ret x } ret 0 }; f()", 5},
+		{`for (c in "abc") { if (c == "b") { ret 1 } }`, 1},
+		{`for (k in {"a": 1}) { if (k == "a") { ret 1 } }`, 1},
+		{"for (x in [1]) { let y = 2 }; let y = 3; y", 3},
 	}
 
 	for _, tt := range tests {
@@ -393,7 +1621,72 @@ if (10 > 1) {
 }
 `, "unknown operator: BOOLEAN + BOOLEAN"},
 		{"hoge;", "identifier not found: hoge"},
+		{"~true", "unknown operator: ~BOOLEAN"},
+		{"x = 1", "identifier not found: x"},
+		{`throw "bad input"`, "bad input"},
+		{`let f = fn() { throw [1, 2] }; f(); 3`, "[1, 2]"},
+		{`try { throw "a" } catch (e) { throw "b" }`, "b"},
+		{`try { throw "a" } finally { 1 }`, "a"},
+		{`try { 1 } finally { throw "b" }`, "b"},
+		{`parse_int(1)`, "argument to `parse_int` must be STRING. got=INTEGER"},
+		{"if (true) { let x = 1 }; x", "identifier not found: x"},
+		{"true & false", "unknown operator: BOOLEAN & BOOLEAN"},
+		{"2 ** -1", "negative exponent: 2 ** -1"},
+		{"1 / 0", "division by zero: 1 / 0"},
+		{"(2 ** 64) % 0", "division by zero: 18446744073709551616 % 0"},
+		{"2 ** (2 ** 64)", "integer too large: 2 ** 18446744073709551616"},
+		{"3 ** 100000000", "integer too large: 3 ** 100000000"},
+		{"1 << 100000000", "integer too large: 1 << 100000000"},
+		{"(2 ** 64) ** (2 ** 58)", "integer too large: 18446744073709551616 ** 288230376151711744"},
+		{"1 << 9223372036854775807", "integer too large: 1 << 9223372036854775807"},
+		{"2 ** 64 & true", "type mismatch: INTEGER & BOOLEAN"},
+		{"let x = 0; 5 % x", "division by zero: 5 % 0"},
+		{"1.5d / 0", "division by zero: 1.5 / 0"},
+		{"1.5d % 0.00d", "division by zero: 1.5 % 0.00"},
+		{"1.5d ** 0.5d", "exponent must be a non-negative integer: 1.5 ** 0.5"},
+		{"1.5d & 1", "unknown operator: DECIMAL & DECIMAL"},
+		{`1.5d + "a"`, "type mismatch: DECIMAL + STRING"},
+		{`round(1.5d, 0, "sideways")`, `unknown rounding mode: "sideways"`},
+		{"0.1d ** 100000000000", "decimal scale too large: 0.1 ** 100000000000"},
+		{"1.5d ** (2 ** 64)", "integer too large: 1.5 ** 18446744073709551616"},
+		{"(2d ** 64) ** (2 ** 58)", "integer too large: 18446744073709551616 ** 288230376151711744"},
+		{"divide(1, 0.0d, 2)", "division by zero: 1 / 0.0"},
+		{`divide(1, "3", 2)`, "the second argument to `divide` must be DECIMAL. got=STRING"},
+		{"divide(1, 3, 2, 1)", "the fourth argument to `divide` must be STRING. got=INTEGER"},
+		{"round(1.5d, 16000000)", "decimal places out of range: 16000000"},
+		{"divide(1, 3, 1001)", "decimal places out of range: 1001"},
+		{"round(1.5d, -1)", "decimal places out of range: -1"},
+		{"1 << -1", "negative shift count: 1 << -1"},
+		{`1.."a"`, "range bound must be INTEGER. got=STRING"},
+		{"1..10 step 0", "range step must not be zero"},
+		{"for (x in 5) { x }", "not iterable: INTEGER"},
+		{"for (x in 1..3) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{`1 in "abc"`, "unknown operator: INTEGER in STRING"},
+		{`[1] in {"a": 1}`, "unusable as hash key: ARRAY"},
+		{`"-" * -1`, "negative repeat count: -1"},
+		{"[1] * (2 ** 64)", "repeat count too large: 18446744073709551616"},
+		{"[1] * -(2 ** 64)", "negative repeat count: -18446744073709551616"},
+		{`-(2 ** 64) * "a"`, "negative repeat count: -18446744073709551616"},
+		{`"ab" * 100000000`, "repeat count too large: 100000000"},
+		{`"a" * "b"`, "unknown operator: STRING * STRING"},
+		{"[1] - [1]", "unknown operator: ARRAY - ARRAY"},
+		{`[1] + "a"`, "type mismatch: ARRAY + STRING"},
+		{"[1] * 1.5d", "type mismatch: ARRAY * DECIMAL"},
 		{`"hello" - "world"`, "unknown operator: STRING - STRING"},
+		{`1 < "a"`, "type mismatch: INTEGER < STRING"},
+		{"{} > {}", "unknown operator: HASH > HASH"},
+		{`[1] < ["a"]`, "unknown operator: ARRAY < ARRAY"},
+		{"true < false", "unknown operator: BOOLEAN < BOOLEAN"},
+		{"to_json([fn() {}])", "cannot convert FUNCTION to JSON"},
+		{"keys([1])", "argument to `keys` must be HASH. got=ARRAY"},
+		{"#{[1]}", "unusable as set element: ARRAY"},
+		{"let (a, b) = (1, 2, 3);", "cannot destructure 3 values into 2 names"},
+		{"let (a, b) = 1;", "cannot destructure INTEGER"},
+		{"(1, 2) + (3,)", "unknown operator: TUPLE + TUPLE"},
+		{"[1] in #{1}", "unusable as set element: ARRAY"},
+		{"#{1} + #{2}", "unknown operator: SET + SET"},
+		{"add([1], 2)", "the first argument to `add` must be SET. got=ARRAY"},
+		{"remove(#{1}, fn() {})", "unusable as set element: FUNCTION"},
 		{`{"name": "monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
 	}
 
@@ -412,6 +1705,103 @@ if (10 > 1) {
 	}
 }
 
+func TestDecimalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"12.50d", "12.50"},
+		{"-0.05d", "-0.05"},
+		{"3d", "3"},
+		{"0.1d + 0.2d", "0.3"},
+		{"12.50d - 0.5d", "12.00"},
+		{"1.5d * 1.5d", "2.25"},
+		{"2 * 1.50d", "3.00"},
+		{"1.50d + 1", "2.50"},
+		{"10.00d / 4", "2.5000000000000000"},
+		{"1d / 3", "0.3333333333333333"},
+		{"2d / 3", "0.6666666666666667"},
+		{"10.5d % 3", "1.5"},
+		{"-10.5d % 3", "-1.5"},
+		{"1.1d ** 2", "1.21"},
+		{"-(1.25d)", "-1.25"},
+		{`decimal("19.99")`, "19.99"},
+		{`decimal(" -7.250 ")`, "-7.250"},
+		{"decimal(42)", "42"},
+		{"round(2.345d, 2)", "2.34"},
+		{"round(2.355d, 2)", "2.36"},
+		{`round(2.345d, 2, "half_up")`, "2.35"},
+		{`round(-2.345d, 2, "half_down")`, "-2.34"},
+		{`round(2.341d, 2, "up")`, "2.35"},
+		{`round(2.349d, 2, "down")`, "2.34"},
+		{`round(-2.341d, 2, "floor")`, "-2.35"},
+		{`round(-2.349d, 2, "ceiling")`, "-2.34"},
+		{"round(5, 2)", "5.00"},
+		{"divide(1, 3, 2)", "0.33"},
+		{`divide(2d, 3, 4, "down")`, "0.6666"},
+		{`divide(10.00d, 4, 1, "half_up")`, "2.5"},
+		{"1d ** 100000000000", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testDecimalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestDecimalComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.0d == 1.00d", true},
+		{"1.0d == 1", true},
+		{"1.01d != 1", true},
+		{"0.1d + 0.2d == 0.3d", true},
+		{"1.5d < 2", true},
+		{"-1.5d > -1.49d", false},
+		{"3 > 2.99d", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestDecimalConversion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"int(12.99d)", 12},
+		{"int(-12.99d)", -12},
+		{"int(7)", 7},
+		{"str(12.50d)", "12.50"},
+		{"str(42)", "42"},
+		{`str("a")`, "a"},
+		{`decimal("abc")["message"]`, `invalid decimal: "abc"`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		}
+	}
+}
+
 func TestBuiltInFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -422,6 +1812,7 @@ func TestBuiltInFunctions(t *testing.T) {
 		{`len("hello world")`, 11},
 		{`len(1)`, "argument to `len` not supported. got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len(1..5)`, 5},
 	}
 
 	for _, tt := range tests {
@@ -452,6 +1843,14 @@ func testEval(input string) object.Object {
 	return Eval(program, env)
 }
 
+func testEvalWithEnv(input string, env *object.Environment) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	return Eval(program, env)
+}
+
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	result, ok := obj.(*object.Integer)
 	if !ok {
@@ -466,6 +1865,34 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testBigIntObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.BigInt)
+	if !ok {
+		t.Errorf("obj not *object.BigInt. got=%T(%+v)", obj, obj)
+		return false
+	}
+	if result.Value.String() != expected {
+		t.Errorf("obj has a wrong value. got=%s, expected=%s", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testDecimalObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.Decimal)
+	if !ok {
+		t.Errorf("obj not *object.Decimal. got=%T(%+v)", obj, obj)
+		return false
+	}
+	if result.Inspect() != expected {
+		t.Errorf("obj has a wrong value. got=%s, expected=%s", result.Inspect(), expected)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -487,3 +1914,24 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func testInspectObject(t *testing.T, input string, obj object.Object, expected string) bool {
+	if obj == nil || obj.Inspect() != expected {
+		t.Errorf("wrong output for %s. expected=%q, got=%v", input, expected, obj)
+		return false
+	}
+	return true
+}
+
+func testErrorObject(t *testing.T, input string, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("no error object returned for %s. got=%T(%+v)", input, obj, obj)
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message for %s. expected=%q, got=%q", input, expected, errObj.Message)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package evaluator
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/yuzuy/yoru/object"
+)
+
+// generatorMessage is sent from a generator body to its consumer: a
+// yielded value, or done with the Error the body failed with, if any.
+type generatorMessage struct {
+	value object.Object
+	done  bool
+}
+
+// errGeneratorAbandoned is raised as a panic at the pending yield of a
+// generator that was garbage collected before it finished, to end its
+// body without running any more Yoru code.
+var errGeneratorAbandoned = errors.New("generator abandoned")
+
+// newGenerator returns the generator for the call of fn with args whose
+// frame is frame. The body runs on its own goroutine from the first
+// request for a value, and it and the consumer hand control to each other
+// at every yield, so that only one of them is evaluating at any time.
+// Tasks may share a generator; one that resumes it while another is
+// waiting for a value gets an error.
+//
+// Closing a suspended generator makes its pending yield return from the
+// body, which cannot be caught, so that its finally blocks and deferred
+// calls run while the closer waits. A for loop closes the generator it
+// leaves early, and the Scheduler of the frame closes those still
+// suspended when the Eval running them returns, so that no body outlives
+// it. A generator dropped before then may be abandoned when it is garbage
+// collected instead: its body ends without running its cleanup.
+func newGenerator(frame *object.Frame, fn *object.Function, args []object.Object) *object.Generator {
+	var (
+		// resume carries true to resume the body and false to close it,
+		// and is closed to abandon it
+		resume = make(chan bool)
+		values = make(chan generatorMessage)
+
+		mu                     sync.Mutex
+		started, running, done bool
+		// untrack removes the generator from those the Scheduler closes
+		// once it has started, and closeGen holds no reference to the
+		// generator, so that it can still be collected
+		untrack  func()
+		closeGen func() object.Object
+	)
+
+	frame.Yield = func(v object.Object) object.Object {
+		values <- generatorMessage{value: v}
+		next, ok := <-resume
+		if !ok {
+			panic(errGeneratorAbandoned)
+		}
+		if !next {
+			return &object.ReturnValue{Value: Null}
+		}
+		return nil
+	}
+
+	run := func() {
+		defer func() {
+			if r := recover(); r != nil && r != errGeneratorAbandoned {
+				panic(r)
+			}
+		}()
+
+		extendedEnv := extendFunctionEnv(fn, args, frame)
+		result := evalBody(fn.Body.Statements, extendedEnv)
+		result = runDeferred(frame, result)
+
+		msg := generatorMessage{done: true}
+		if err, ok := result.(*object.Error); ok {
+			if err.Trace == nil {
+				err.Trace = frame.Trace()
+			}
+			msg.value = err
+		}
+		values <- msg
+	}
+
+	// acquire marks the generator as running, and reports whether it was
+	// started before, unless it is done or already running.
+	acquire := func() (wasStarted bool, err object.Object, ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return false, nil, false
+		}
+		if running {
+			return false, newErrorKind(object.ErrorKind, "generator already running"), false
+		}
+		running = true
+		wasStarted = started
+		if !started {
+			started = true
+			untrack = frame.Sched.TrackGenerator(closeGen)
+		}
+		return wasStarted, nil, true
+	}
+	// finish marks the generator as done. mu must be held.
+	finish := func() {
+		done = true
+		if untrack != nil {
+			untrack()
+		}
+	}
+	release := func(finished bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		running = false
+		if finished {
+			finish()
+		}
+	}
+
+	resumeGen := func() (object.Object, bool) {
+		wasStarted, err, ok := acquire()
+		if !ok {
+			return err, err != nil
+		}
+
+		if wasStarted {
+			resume <- true
+		} else {
+			go run()
+		}
+		msg := <-values
+		release(msg.done)
+
+		if msg.done {
+			return msg.value, msg.value != nil
+		}
+		return msg.value, true
+	}
+	closeGen = func() object.Object {
+		wasStarted, err, ok := acquire()
+		if !ok {
+			if err != nil {
+				return err
+			}
+			return Null
+		}
+		if !wasStarted {
+			release(true)
+			return Null
+		}
+
+		// a yield in a finally block while closing returns from the body
+		// again
+		var msg generatorMessage
+		for !msg.done {
+			resume <- false
+			msg = <-values
+		}
+		release(true)
+
+		if msg.value != nil {
+			return msg.value
+		}
+		return Null
+	}
+	gen := object.NewGenerator(fn.Name, resumeGen, closeGen)
+	runtime.SetFinalizer(gen, func(*object.Generator) {
+		mu.Lock()
+		defer mu.Unlock()
+		// the Scheduler may be closing it
+		if !started || running || done {
+			return
+		}
+		finish()
+		close(resume)
+	})
+
+	return gen
+}
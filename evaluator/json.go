@@ -0,0 +1,105 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuzuy/yoru/object"
+)
+
+// writeJSON encodes obj as JSON into out. Hashes keep their insertion
+// order, and their integer, boolean and null keys are written as strings.
+func writeJSON(out *bytes.Buffer, obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+	case *object.Boolean, *object.Integer, *object.BigInt, *object.Decimal:
+		out.WriteString(obj.Inspect())
+	case *object.String:
+		writeJSONString(out, obj.Value)
+	case *object.Array:
+		return writeJSONArray(out, obj.Elements)
+	case *object.Tuple:
+		return writeJSONArray(out, obj.Elements)
+	case *object.Set:
+		return writeJSONArray(out, obj.Elements())
+	case *object.Hash:
+		out.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			switch key := pair.Key.(type) {
+			case *object.String:
+				writeJSONString(out, key.Value)
+			case *object.Integer, *object.BigInt, *object.Boolean, *object.Null:
+				writeJSONString(out, key.Inspect())
+			default:
+				return fmt.Errorf("cannot convert %s hash key to JSON", key.Type())
+			}
+			out.WriteByte(':')
+			if err := writeJSON(out, pair.Value); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case *object.StructInstance:
+		out.WriteByte('{')
+		for i, f := range obj.Fields {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, obj.Struct.Fields[i])
+			out.WriteByte(':')
+			if err := writeJSON(out, f); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot convert %s to JSON", obj.Type())
+	}
+
+	return nil
+}
+
+func writeJSONArray(out *bytes.Buffer, elements []object.Object) error {
+	out.WriteByte('[')
+	for i, el := range elements {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if err := writeJSON(out, el); err != nil {
+			return err
+		}
+	}
+	out.WriteByte(']')
+
+	return nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	out.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20:
+			out.WriteString(`\u00`)
+			out.WriteByte(hex[r>>4])
+			out.WriteByte(hex[r&0xf])
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+}
@@ -11,15 +11,24 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+
+	line   int
+	column int
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) readChar() {
+	if l.position < len(l.input) && l.input[l.position] == '\n' && l.readPosition > 0 {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	if len(l.input) <= l.readPosition {
 		l.ch = 0
 	} else {
@@ -38,10 +47,18 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	pos := token.Position{Line: l.line, Column: l.column}
+	tok := l.readToken()
+	tok.Pos = pos
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -49,6 +66,11 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.Arrow, Literal: literal}
 		} else {
 			tok = newToken(token.Assign, l.ch)
 		}
@@ -64,17 +86,74 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.Plus, l.ch)
 	case '-':
-		tok = newToken(token.Minus, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.ThinArrow, Literal: literal}
+		} else {
+			tok = newToken(token.Minus, l.ch)
+		}
 	case '*':
-		tok = newToken(token.Asterisk, l.ch)
+		if l.peekChar() == '*' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.Power, Literal: literal}
+		} else {
+			tok = newToken(token.Asterisk, l.ch)
+		}
 	case '%':
 		tok = newToken(token.Mod, l.ch)
 	case '/':
 		tok = newToken(token.Slash, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.LShift, Literal: literal}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.RShift, Literal: literal}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		tok = newToken(token.Ampersand, l.ch)
+	case '|':
+		tok = newToken(token.Pipe, l.ch)
+	case '^':
+		tok = newToken(token.Caret, l.ch)
+	case '~':
+		tok = newToken(token.Tilde, l.ch)
+	case '.':
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '<' {
+				l.readChar()
+				tok = token.Token{Type: token.DotDotLT, Literal: "..<"}
+			} else {
+				tok = token.Token{Type: token.DotDot, Literal: ".."}
+			}
+		} else {
+			tok = newToken(token.Dot, l.ch)
+		}
+	case '#':
+		if l.peekChar() == '{' {
+			l.readChar()
+			tok = token.Token{Type: token.SetBrace, Literal: "#{"}
+		} else {
+			tok = newToken(token.Illegal, l.ch)
+		}
+	case '?':
+		tok = newToken(token.Question, l.ch)
 	case ':':
 		tok = newToken(token.Colon, l.ch)
 	case ';':
@@ -109,9 +188,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookUpIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.Int
-			tok.Literal = l.readNumber()
-			return tok
+			return l.readNumber()
 		} else {
 			tok = newToken(token.Illegal, l.ch)
 		}
@@ -176,12 +253,33 @@ func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer, or a decimal such as 12.50d or 3d.
+// The d suffix is not part of the literal.
+func (l *Lexer) readNumber() token.Token {
 	position := l.position
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	fraction := false
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		fraction = true
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+	literal := l.input[position:l.position]
+
+	if l.ch == 'd' {
+		l.readChar()
+		return token.Token{Type: token.Decimal, Literal: literal}
+	}
+	if fraction {
+		return token.Token{Type: token.Illegal, Literal: "decimal literal requires d suffix: " + literal}
+	}
+
+	return token.Token{Type: token.Int, Literal: literal}
 }
 
 func isDigit(ch byte) bool {
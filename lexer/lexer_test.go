@@ -37,6 +37,20 @@ case "foo":
 case "bar":
   ret 2;
 };
+x => x;
+a & b | c ^ ~d;
+1 << 2 >> 3 ** 4;
+for (i in 1..10) {}
+0..<n;
+12.50d + 3d;
+1..2;
+#{1};
+struct P { x }; p.x;
+enum E { A }
+fn(x: int) -> bool {}
+yield x;
+spawn f();
+select {}
 `
 
 	tests := []struct {
@@ -153,6 +167,89 @@ case "bar":
 		{token.Semicolon, ";"},
 		{token.Rbrace, "}"},
 		{token.Semicolon, ";"},
+		{token.Ident, "x"},
+		{token.Arrow, "=>"},
+		{token.Ident, "x"},
+		{token.Semicolon, ";"},
+		{token.Ident, "a"},
+		{token.Ampersand, "&"},
+		{token.Ident, "b"},
+		{token.Pipe, "|"},
+		{token.Ident, "c"},
+		{token.Caret, "^"},
+		{token.Tilde, "~"},
+		{token.Ident, "d"},
+		{token.Semicolon, ";"},
+		{token.Int, "1"},
+		{token.LShift, "<<"},
+		{token.Int, "2"},
+		{token.RShift, ">>"},
+		{token.Int, "3"},
+		{token.Power, "**"},
+		{token.Int, "4"},
+		{token.Semicolon, ";"},
+		{token.For, "for"},
+		{token.Lparen, "("},
+		{token.Ident, "i"},
+		{token.In, "in"},
+		{token.Int, "1"},
+		{token.DotDot, ".."},
+		{token.Int, "10"},
+		{token.Rparen, ")"},
+		{token.Lbrace, "{"},
+		{token.Rbrace, "}"},
+		{token.Int, "0"},
+		{token.DotDotLT, "..<"},
+		{token.Ident, "n"},
+		{token.Semicolon, ";"},
+		{token.Decimal, "12.50"},
+		{token.Plus, "+"},
+		{token.Decimal, "3"},
+		{token.Semicolon, ";"},
+		{token.Int, "1"},
+		{token.DotDot, ".."},
+		{token.Int, "2"},
+		{token.Semicolon, ";"},
+		{token.SetBrace, "#{"},
+		{token.Int, "1"},
+		{token.Rbrace, "}"},
+		{token.Semicolon, ";"},
+		{token.Struct, "struct"},
+		{token.Ident, "P"},
+		{token.Lbrace, "{"},
+		{token.Ident, "x"},
+		{token.Rbrace, "}"},
+		{token.Semicolon, ";"},
+		{token.Ident, "p"},
+		{token.Dot, "."},
+		{token.Ident, "x"},
+		{token.Semicolon, ";"},
+		{token.Enum, "enum"},
+		{token.Ident, "E"},
+		{token.Lbrace, "{"},
+		{token.Ident, "A"},
+		{token.Rbrace, "}"},
+		{token.Function, "fn"},
+		{token.Lparen, "("},
+		{token.Ident, "x"},
+		{token.Colon, ":"},
+		{token.Ident, "int"},
+		{token.Rparen, ")"},
+		{token.ThinArrow, "->"},
+		{token.Ident, "bool"},
+		{token.Lbrace, "{"},
+		{token.Rbrace, "}"},
+		{token.Yield, "yield"},
+		{token.Ident, "x"},
+		{token.Semicolon, ";"},
+		{token.Spawn, "spawn"},
+		{token.Ident, "f"},
+		{token.Lparen, "("},
+		{token.Rparen, ")"},
+		{token.Semicolon, ";"},
+		{token.Select, "select"},
+		{token.Lbrace, "{"},
+		{token.Rbrace, "}"},
 		{token.EOF, ""},
 	}
 
@@ -187,6 +284,20 @@ func TestStringLiteralError(t *testing.T) {
 	}
 }
 
+func TestDecimalLiteralError(t *testing.T) {
+	input := `12.50`
+	l := New(input)
+
+	tok := l.NextToken()
+	if tok.Type != token.Illegal {
+		t.Fatalf("token type wrong. expected=%s, got=%s", token.Illegal, tok.Type)
+	}
+	errMsg := "decimal literal requires d suffix: 12.50"
+	if tok.Literal != errMsg {
+		t.Fatalf("error message wrong. expected=%s, got=%s", errMsg, tok.Literal)
+	}
+}
+
 func TestEscapeCharacters(t *testing.T) {
 	input := `"\n\r\t\"\'\\"`
 	l := New(input)
@@ -199,3 +310,35 @@ func TestEscapeCharacters(t *testing.T) {
 		t.Fatalf("tok.Literal wrong. got=%s", tok.Literal)
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := `let x = "a\nb";
+  x + 10;`
+	l := New(input)
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{"let", token.Position{Line: 1, Column: 1}},
+		{"x", token.Position{Line: 1, Column: 5}},
+		{"=", token.Position{Line: 1, Column: 7}},
+		{"a\nb", token.Position{Line: 1, Column: 9}},
+		{";", token.Position{Line: 1, Column: 15}},
+		{"x", token.Position{Line: 2, Column: 3}},
+		{"+", token.Position{Line: 2, Column: 5}},
+		{"10", token.Position{Line: 2, Column: 7}},
+		{";", token.Position{Line: 2, Column: 9}},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - position wrong. expected=%s, got=%s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
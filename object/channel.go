@@ -0,0 +1,216 @@
+package object
+
+// Task is a function call started by spawn, running concurrently with the
+// code that started it.
+type Task struct {
+	Name    string
+	sched   *Scheduler
+	done    bool
+	result  Object
+	waiters []*waiter
+}
+
+// NewTask returns a task of s. Its thread must be recorded with
+// s.StartTask.
+func NewTask(s *Scheduler, name string) *Task {
+	return &Task{Name: name, sched: s}
+}
+
+func (t *Task) Type() Type { return TaskObj }
+func (t *Task) Inspect() string {
+	if t.Name == "" {
+		return "task"
+	}
+	return "task " + t.Name
+}
+
+// Finish records the result of the call and wakes the tasks waiting for
+// it. It must be called exactly once, before the thread of the call ends.
+func (t *Task) Finish(result Object) {
+	s := t.sched
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.done = true
+	t.result = result
+	for _, w := range t.waiters {
+		if !w.done {
+			s.complete(w, 0, result, true, nil)
+		}
+	}
+	t.waiters = nil
+}
+
+// Wait blocks a thread of s until the call has finished and returns its
+// result. It fails like Scheduler.Select.
+func (t *Task) Wait(s *Scheduler) (Object, error) {
+	if t.sched != s {
+		return nil, ErrForeign
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.done {
+		return t.result, nil
+	}
+	if err := s.canBlock(); err != nil {
+		return nil, err
+	}
+
+	w := newWaiter()
+	t.waiters = append(t.waiters, w)
+	s.waitFor(w)
+	return w.value, w.err
+}
+
+// Channel passes values between tasks. A receive waits for a value unless
+// the channel is buffered and holds one; a send waits for a receiver
+// unless there is room in the buffer.
+type Channel struct {
+	sched  *Scheduler
+	size   int
+	buf    []Object
+	closed bool
+	recvq  []pending
+	sendq  []pending
+}
+
+// pending is a case of a blocked operation waiting on a channel.
+type pending struct {
+	w     *waiter
+	index int
+	value Object
+}
+
+// NewChannel returns a channel of s with a buffer for size values.
+func NewChannel(s *Scheduler, size int) *Channel {
+	return &Channel{sched: s, size: size}
+}
+
+func (c *Channel) Type() Type      { return ChannelObj }
+func (c *Channel) Inspect() string { return "channel" }
+
+// Send sends v on c from a thread of s. It fails like Scheduler.Select.
+func (c *Channel) Send(s *Scheduler, v Object) error {
+	_, _, _, err := s.Select([]SelectCase{{Chan: c, Send: true, Value: v}}, true)
+	return err
+}
+
+// Receive returns the next value sent on c to a thread of s. ok is false
+// once c has been closed and all values sent before have been received.
+// It fails like Scheduler.Select.
+func (c *Channel) Receive(s *Scheduler) (obj Object, ok bool, err error) {
+	_, obj, ok, err = s.Select([]SelectCase{{Chan: c}}, true)
+	return obj, ok, err
+}
+
+// Close closes c, and reports false if it already was.
+func (c *Channel) Close() bool {
+	s := c.sched
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.closed = true
+
+	for _, p := range c.recvq {
+		if !p.w.done {
+			s.complete(p.w, p.index, nil, false, nil)
+		}
+	}
+	for _, p := range c.sendq {
+		if !p.w.done {
+			s.complete(p.w, p.index, nil, false, ErrClosedChannel)
+		}
+	}
+	c.recvq, c.sendq = nil, nil
+	return true
+}
+
+// trySend sends v without blocking, and reports whether it could.
+func (c *Channel) trySend(v Object) (bool, error) {
+	if c.closed {
+		return false, ErrClosedChannel
+	}
+	if r, ok := pop(&c.recvq); ok {
+		c.sched.complete(r.w, r.index, v, true, nil)
+		return true, nil
+	}
+	if len(c.buf) < c.size {
+		c.buf = append(c.buf, v)
+		return true, nil
+	}
+	return false, nil
+}
+
+// tryReceive receives without blocking. ready is false if it could not.
+func (c *Channel) tryReceive() (v Object, ok, ready bool) {
+	if len(c.buf) > 0 {
+		v = c.buf[0]
+		c.buf = c.buf[1:]
+		// a waiting sender takes the freed slot
+		if s, ok := pop(&c.sendq); ok {
+			c.buf = append(c.buf, s.value)
+			c.sched.complete(s.w, s.index, nil, false, nil)
+		}
+		return v, true, true
+	}
+	if s, ok := pop(&c.sendq); ok {
+		c.sched.complete(s.w, s.index, nil, false, nil)
+		return s.value, true, true
+	}
+	if c.closed {
+		return nil, false, true
+	}
+	return nil, false, false
+}
+
+// pop removes the first case in q whose operation is still blocked.
+func pop(q *[]pending) (pending, bool) {
+	for len(*q) > 0 {
+		p := (*q)[0]
+		*q = (*q)[1:]
+		if !p.w.done {
+			return p, true
+		}
+	}
+	return pending{}, false
+}
+
+// remove drops the cases of w from q.
+func remove(q []pending, w *waiter) []pending {
+	kept := q[:0]
+	for _, p := range q {
+		if p.w != w {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// Iterator receives the values sent on c, by a thread of the Scheduler of
+// c, until it is closed.
+func (c *Channel) Iterator() Iterator { return channelIterator{c} }
+
+type channelIterator struct{ c *Channel }
+
+// Next yields the Error a blocked receive fails with, if any.
+func (it channelIterator) Next() (Object, bool) {
+	v, ok, err := it.c.Receive(it.c.sched)
+	if err != nil {
+		return &Error{Kind: BlockingErrorKind(err), Message: err.Error()}, true
+	}
+	return v, ok
+}
+
+// BlockingErrorKind returns the kind of Error for a failure of a channel
+// or task operation.
+func BlockingErrorKind(err error) string {
+	switch err {
+	case ErrDeadlock:
+		return DeadlockErrorKind
+	case ErrCancelled:
+		return CancelledErrorKind
+	default:
+		return ValueErrorKind
+	}
+}
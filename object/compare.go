@@ -0,0 +1,167 @@
+package object
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Equal reports whether a and b are structurally equal. Numbers compare by
+// value across Integer, BigInt and Decimal, arrays and hashes compare
+// element by element, and values without structure such as functions
+// compare by identity.
+func Equal(a, b Object) bool {
+	if a == b {
+		return true
+	}
+
+	// the most common hash keys are compared without allocating, before
+	// numbers of different types are converted to Decimal
+	switch a := a.(type) {
+	case *Integer:
+		if b, ok := b.(*Integer); ok {
+			return a.Value == b.Value
+		}
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	}
+
+	if an, ok := toNumber(a); ok {
+		bn, ok := toNumber(b)
+		return ok && an.Cmp(bn) == 0
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && equalElements(a.Elements, b.Elements)
+	case *Tuple:
+		b, ok := b.(*Tuple)
+		return ok && equalElements(a.Elements, b.Elements)
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			other, ok := b.Get(pair.Key.(Hashable))
+			if !ok || !Equal(pair.Value, other) {
+				return false
+			}
+		}
+		return true
+	case *StructInstance:
+		b, ok := b.(*StructInstance)
+		return ok && a.Struct == b.Struct && equalElements(a.Fields, b.Fields)
+	case *EnumValue:
+		b, ok := b.(*EnumValue)
+		return ok && a.Variant == b.Variant && equalElements(a.Fields, b.Fields)
+	case *Set:
+		b, ok := b.(*Set)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, el := range a.Elements() {
+			if !b.Contains(el.(Hashable)) {
+				return false
+			}
+		}
+		return true
+	case *Range:
+		b, ok := b.(*Range)
+		return ok && *a == *b
+	case *ErrorValue:
+		b, ok := b.(*ErrorValue)
+		return ok && a.Error.Kind == b.Error.Kind && a.Error.Message == b.Error.Message
+	default:
+		return false
+	}
+}
+
+// Compare orders a and b, returning -1, 0 or +1. Numbers are ordered by
+// value, strings by their bytes and arrays and tuples lexicographically
+// by their elements. ok is false when the two values have no defined order.
+func Compare(a, b Object) (result int, ok bool) {
+	if an, ok := toNumber(a); ok {
+		bn, ok := toNumber(b)
+		if !ok {
+			return 0, false
+		}
+		return an.Cmp(bn), true
+	}
+
+	switch a := a.(type) {
+	case *String:
+		b, ok := b.(*String)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a.Value, b.Value), true
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok {
+			return 0, false
+		}
+		return compareElements(a.Elements, b.Elements)
+	case *Tuple:
+		b, ok := b.(*Tuple)
+		if !ok {
+			return 0, false
+		}
+		return compareElements(a.Elements, b.Elements)
+	default:
+		return 0, false
+	}
+}
+
+func equalElements(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareElements orders a and b lexicographically.
+func compareElements(a, b []Object) (int, bool) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		c, ok := Compare(a[i], b[i])
+		if !ok {
+			return 0, false
+		}
+		if c != 0 {
+			return c, true
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1, true
+	case len(a) > len(b):
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func toNumber(obj Object) (*Decimal, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return &Decimal{Unscaled: big.NewInt(obj.Value)}, true
+	case *BigInt:
+		return &Decimal{Unscaled: obj.Value}, true
+	case *Decimal:
+		return obj, true
+	default:
+		return nil, false
+	}
+}
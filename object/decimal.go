@@ -0,0 +1,205 @@
+package object
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RoundingMode selects how a Decimal is rounded when digits are dropped.
+type RoundingMode int
+
+const (
+	RoundHalfEven RoundingMode = iota
+	RoundHalfUp
+	RoundHalfDown
+	RoundUp
+	RoundDown
+	RoundCeiling
+	RoundFloor
+)
+
+var roundingModeNames = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+// ParseRoundingMode returns the RoundingMode called name in Yoru scripts,
+// such as "half_even" or "floor".
+func ParseRoundingMode(name string) (RoundingMode, bool) {
+	mode, ok := roundingModeNames[name]
+	return mode, ok
+}
+
+// Decimal is an exact decimal number with the value Unscaled * 10^-Scale.
+// The scale is kept as written, so 12.50d prints as 12.50.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+func (d *Decimal) Type() Type { return DecimalObj }
+func (d *Decimal) Inspect() string {
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if d.Scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+	point := len(digits) - d.Scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// ParseDecimal parses a decimal such as "-12.50" without an exponent.
+func ParseDecimal(s string) (*Decimal, error) {
+	str := strings.TrimSpace(s)
+	scale := 0
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		scale = len(str) - i - 1
+		str = str[:i] + str[i+1:]
+	}
+
+	unscaled, ok := new(big.Int).SetString(str, 10)
+	if !ok || strings.ContainsAny(str, "_xXoObB") {
+		return nil, fmt.Errorf("invalid decimal: %q", s)
+	}
+
+	return &Decimal{Unscaled: unscaled, Scale: scale}, nil
+}
+
+// NewDecimalFromInt returns v as a Decimal with scale 0.
+func NewDecimalFromInt(v *big.Int) *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Set(v), Scale: 0}
+}
+
+// Rescale returns d with the given scale, rounding with mode if digits
+// have to be dropped.
+func (d *Decimal) Rescale(scale int, mode RoundingMode) *Decimal {
+	if scale >= d.Scale {
+		return &Decimal{Unscaled: new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale)), Scale: scale}
+	}
+	return &Decimal{Unscaled: divRound(d.Unscaled, pow10(d.Scale-scale), mode), Scale: scale}
+}
+
+// Truncate returns the integer part of d.
+func (d *Decimal) Truncate() *big.Int {
+	return new(big.Int).Quo(d.Unscaled, pow10(d.Scale))
+}
+
+// Reduce returns d with trailing fractional zeros dropped, so that equal
+// decimals such as 1.50 and 1.5 have the same form.
+func (d *Decimal) Reduce() *Decimal {
+	unscaled, scale := d.Unscaled, d.Scale
+	ten := big.NewInt(10)
+	for scale > 0 {
+		q, r := new(big.Int).QuoRem(unscaled, ten, new(big.Int))
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled, scale = q, scale-1
+	}
+	return &Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+// Cmp compares d and o numerically, ignoring their scales.
+func (d *Decimal) Cmp(o *Decimal) int {
+	a, b := alignDecimals(d, o)
+	return a.Cmp(b)
+}
+
+func (d *Decimal) Neg() *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Neg(d.Unscaled), Scale: d.Scale}
+}
+
+func (d *Decimal) Add(o *Decimal) *Decimal {
+	a, b := alignDecimals(d, o)
+	return &Decimal{Unscaled: a.Add(a, b), Scale: maxInt(d.Scale, o.Scale)}
+}
+
+func (d *Decimal) Sub(o *Decimal) *Decimal {
+	a, b := alignDecimals(d, o)
+	return &Decimal{Unscaled: a.Sub(a, b), Scale: maxInt(d.Scale, o.Scale)}
+}
+
+func (d *Decimal) Mul(o *Decimal) *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Mul(d.Unscaled, o.Unscaled), Scale: d.Scale + o.Scale}
+}
+
+// Quo returns d / o rounded to scale with mode. o must not be zero.
+func (d *Decimal) Quo(o *Decimal, scale int, mode RoundingMode) *Decimal {
+	// d / o * 10^scale == d.Unscaled * 10^(o.Scale+scale) / (o.Unscaled * 10^d.Scale)
+	num := new(big.Int).Mul(d.Unscaled, pow10(o.Scale+scale))
+	den := new(big.Int).Mul(o.Unscaled, pow10(d.Scale))
+	return &Decimal{Unscaled: divRound(num, den, mode), Scale: scale}
+}
+
+// Rem returns the remainder of d / o truncated towards zero. o must not
+// be zero.
+func (d *Decimal) Rem(o *Decimal) *Decimal {
+	a, b := alignDecimals(d, o)
+	return &Decimal{Unscaled: a.Rem(a, b), Scale: maxInt(d.Scale, o.Scale)}
+}
+
+func alignDecimals(a, b *Decimal) (*big.Int, *big.Int) {
+	scale := maxInt(a.Scale, b.Scale)
+	x := new(big.Int).Mul(a.Unscaled, pow10(scale-a.Scale))
+	y := new(big.Int).Mul(b.Unscaled, pow10(scale-b.Scale))
+	return x, y
+}
+
+// divRound returns num / den rounded to an integer with mode.
+func divRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	sign := int64(num.Sign() * den.Sign())
+	// half compares the dropped remainder with half of the divisor
+	twice := new(big.Int).Abs(r)
+	half := twice.Lsh(twice, 1).Cmp(new(big.Int).Abs(den))
+
+	var away bool
+	switch mode {
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundCeiling:
+		away = sign > 0
+	case RoundFloor:
+		away = sign < 0
+	case RoundHalfUp:
+		away = half >= 0
+	case RoundHalfDown:
+		away = half > 0
+	case RoundHalfEven:
+		away = half > 0 || (half == 0 && q.Bit(0) == 1)
+	}
+
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,22 +1,56 @@
 package object
 
+import (
+	"sync"
+
+	"github.com/yuzuy/yoru/token"
+)
+
+// Environment binds names to values in one scope. It is safe for
+// concurrent use, as spawned functions share the scopes they close over.
 type Environment struct {
+	mu    sync.RWMutex
 	store map[string]Object
 	outer *Environment
+	frame *Frame
 }
 
+// NewEnvironment returns a top-level environment with its own Frame and
+// Scheduler.
 func NewEnvironment() *Environment {
-	return &Environment{store: make(map[string]Object)}
+	frame := &Frame{Sched: NewScheduler()}
+	return &Environment{store: make(map[string]Object), frame: frame}
 }
 
+// NewEnclosedEnvironment returns a scope nested in outer that shares the
+// Frame of outer.
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-	env := NewEnvironment()
-	env.outer = outer
+	return &Environment{store: make(map[string]Object), outer: outer}
+}
+
+// NewFrameEnvironment returns a scope nested in outer that runs in frame,
+// as done for each function call.
+func NewFrameEnvironment(outer *Environment, frame *Frame) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.frame = frame
 	return env
 }
 
+// Frame returns the Frame of the nearest enclosing function call, or the
+// top-level Frame outside of any call.
+func (e *Environment) Frame() *Frame {
+	for env := e; env != nil; env = env.outer {
+		if env.frame != nil {
+			return env.frame
+		}
+	}
+	return nil
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
 	obj, ok := e.store[name]
+	e.mu.RUnlock()
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
@@ -24,6 +58,82 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
 	e.store[name] = val
+	e.mu.Unlock()
 	return val
 }
+
+// Assign rebinds name in the nearest scope that already defines it.
+// It reports false if name is not bound anywhere in the chain.
+func (e *Environment) Assign(name string, val Object) bool {
+	e.mu.Lock()
+	_, ok := e.store[name]
+	if ok {
+		e.store[name] = val
+	}
+	e.mu.Unlock()
+	if ok {
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
+// Frame holds the state of a single function call. Frames are linked to
+// the frame of their caller, forming the call stack.
+type Frame struct {
+	Function string
+	CallPos  token.Position
+	Caller   *Frame
+	Depth    int
+	// Yield is set on the frame of a generator. It hands a value to the
+	// consumer and returns nil once the next value is requested, or a
+	// ReturnValue that ends the body if the generator is being closed.
+	Yield func(Object) Object
+	// Sched runs the thread of the call, and is shared by all the frames
+	// of a top-level environment.
+	Sched *Scheduler
+
+	deferred []Deferred
+}
+
+// Trace returns the call stack leading to f, innermost call first.
+// The top-level frame is not part of the trace.
+func (f *Frame) Trace() []TraceEntry {
+	trace := []TraceEntry{}
+	for fr := f; fr != nil && fr.Caller != nil; fr = fr.Caller {
+		trace = append(trace, TraceEntry{Function: fr.Function, Pos: fr.CallPos})
+	}
+	return trace
+}
+
+// TraceEntry is a call in a stack trace: the called function and the
+// position of the call site.
+type TraceEntry struct {
+	Function string
+	Pos      token.Position
+}
+
+// Deferred is a call registered by a defer statement.
+type Deferred struct {
+	Fn   Object
+	Args []Object
+	Pos  token.Position
+}
+
+func (f *Frame) Defer(fn Object, args []Object, pos token.Position) {
+	f.deferred = append(f.deferred, Deferred{Fn: fn, Args: args, Pos: pos})
+}
+
+// PopDeferred removes and returns the most recently deferred call.
+func (f *Frame) PopDeferred() (Deferred, bool) {
+	if len(f.deferred) == 0 {
+		return Deferred{}, false
+	}
+	d := f.deferred[len(f.deferred)-1]
+	f.deferred = f.deferred[:len(f.deferred)-1]
+	return d, true
+}
@@ -2,11 +2,16 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash"
 	"hash/fnv"
+	"math/big"
+	"runtime"
 	"strings"
 
 	"github.com/yuzuy/yoru/ast"
+	"github.com/yuzuy/yoru/token"
 )
 
 type Type string
@@ -21,26 +26,164 @@ const (
 	StringObj      = "STRING"
 	ArrayObj       = "ARRAY"
 	HashObj        = "HASH"
+	RangeObj       = "RANGE"
+	ErrorValueObj  = "ERROR_VALUE"
+	DecimalObj     = "DECIMAL"
+	SetObj         = "SET"
+	TupleObj       = "TUPLE"
+	StructObj      = "STRUCT"
+	EnumObj        = "ENUM"
+	EnumVariantObj = "ENUM_VARIANT"
+	GeneratorObj   = "GENERATOR"
+	TaskObj        = "TASK"
+	ChannelObj     = "CHANNEL"
 
 	BuiltInObj = "BUILD-IN"
 )
 
+// builtinTypes holds the Type of every built-in object.
+var builtinTypes = map[Type]bool{
+	NullObj: true, ErrorObj: true, ReturnValueObj: true, IntObj: true,
+	BoolObj: true, FunctionObj: true, StringObj: true, ArrayObj: true,
+	HashObj: true, RangeObj: true, ErrorValueObj: true, DecimalObj: true,
+	SetObj: true, TupleObj: true, StructObj: true, EnumObj: true,
+	EnumVariantObj: true, GeneratorObj: true, TaskObj: true, ChannelObj: true,
+	BuiltInObj: true,
+}
+
+// AnyType is the annotation that accepts every object.
+const AnyType = "any"
+
+// AnnotationTypes maps the names of the built-in types of annotations,
+// other than AnyType, to the Types of the objects they accept.
+var AnnotationTypes = map[string][]Type{
+	"int":       {IntObj},
+	"decimal":   {DecimalObj},
+	"string":    {StringObj},
+	"bool":      {BoolObj},
+	"null":      {NullObj},
+	"array":     {ArrayObj},
+	"hash":      {HashObj},
+	"set":       {SetObj},
+	"tuple":     {TupleObj},
+	"range":     {RangeObj},
+	"fn":        {FunctionObj, BuiltInObj, StructObj, EnumVariantObj},
+	"error":     {ErrorValueObj},
+	"struct":    {StructObj},
+	"enum":      {EnumObj},
+	"generator": {GeneratorObj},
+	"task":      {TaskObj},
+	"channel":   {ChannelObj},
+}
+
+// IsReservedTypeName reports whether a struct or enum may not be called
+// name. That is the case for the built-in types of annotations, which
+// would lose their meaning, and for the Type of every built-in object, as
+// struct and enum values take the name of their type as their Type.
+func IsReservedTypeName(name string) bool {
+	_, ok := AnnotationTypes[name]
+	return ok || name == AnyType || builtinTypes[Type(name)]
+}
+
 type Object interface {
 	Type() Type
 	Inspect() string
 }
 
+// Iterable is implemented by objects that can be walked by a for loop.
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// Iterator yields the elements of an Iterable one at a time. ok is false
+// once the iterator is exhausted. An iterator that fails yields the Error,
+// which ends the iteration.
+type Iterator interface {
+	Next() (obj Object, ok bool)
+}
+
 type Null struct{}
 
 func (n *Null) Type() Type      { return NullObj }
 func (n *Null) Inspect() string { return "null" }
+func (n *Null) HashKey() HashKey {
+	return HashKey{Type: n.Type()}
+}
+
+// Kinds of Error.
+const (
+	ErrorKind           = "Error"
+	TypeErrorKind       = "TypeError"
+	NameErrorKind       = "NameError"
+	ArgumentErrorKind   = "ArgumentError"
+	ValueErrorKind      = "ValueError"
+	ArithmeticErrorKind = "ArithmeticError"
+	RecursionErrorKind  = "RecursionError"
+	DeadlockErrorKind   = "DeadlockError"
+	CancelledErrorKind  = "CancelledError"
+	InternalErrorKind   = "InternalError"
+)
 
+// Error is a failure propagating up the evaluation. Value holds the
+// operand of the throw statement that raised it, if any, and GoStack the
+// Go stack of the panic it was converted from, if recorded.
 type Error struct {
 	Message string
+	Kind    string
+	Pos     token.Position
+	Value   Object
+	Trace   []TraceEntry
+	GoStack string
 }
 
-func (e *Error) Type() Type      { return ErrorObj }
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) Type() Type { return ErrorObj }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("ERROR: %s (%s)", e.Message, e.Pos)
+	}
+	return "ERROR: " + e.Message
+}
+
+// maxTraceLines bounds the calls shown by StackTrace, so that the trace of
+// a runaway recursion stays readable.
+const maxTraceLines = 20
+
+// StackTrace formats the calls the error propagated through, one per line.
+// Long traces are shortened by eliding calls in the middle.
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+
+	for i, t := range e.Trace {
+		if len(e.Trace) > maxTraceLines && i >= maxTraceLines/2 && i < len(e.Trace)-maxTraceLines/2 {
+			if i == maxTraceLines/2 {
+				out.WriteString(fmt.Sprintf("\t... %d more calls ...\n", len(e.Trace)-maxTraceLines))
+			}
+			continue
+		}
+		name := t.Function
+		if name == "" {
+			name = "<anonymous>"
+		}
+		out.WriteString(fmt.Sprintf("\tat %s (called at %s)\n", name, t.Pos))
+	}
+
+	return out.String()
+}
+
+// ErrorValue is an Error held as an ordinary value, as bound by a catch
+// clause. Unlike Error it does not abort evaluation.
+type ErrorValue struct {
+	Error *Error
+}
+
+func (ev *ErrorValue) Type() Type { return ErrorValueObj }
+func (ev *ErrorValue) Inspect() string {
+	kind := ev.Error.Kind
+	if kind == "" {
+		kind = ErrorKind
+	}
+	return kind + ": " + ev.Error.Message
+}
 
 type ReturnValue struct {
 	Value Object
@@ -55,6 +198,25 @@ type Integer struct {
 
 func (i *Integer) Type() Type      { return IntObj }
 func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// BigInt is an integer outside the range of int64. Arithmetic promotes
+// Integer results that overflow to BigInt and demotes them back whenever
+// they fit, so a BigInt never holds a value an Integer could.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() Type      { return IntObj }
+func (b *BigInt) Inspect() string { return b.Value.String() }
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
 
 type Boolean struct {
 	Value bool
@@ -62,11 +224,23 @@ type Boolean struct {
 
 func (b *Boolean) Type() Type      { return BoolObj }
 func (b *Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
 
 type Function struct {
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	// Generator is set for functions containing yield, whose calls
+	// return a Generator.
+	Generator bool
 }
 
 func (f *Function) Type() Type { return FunctionObj }
@@ -94,11 +268,14 @@ type String struct {
 
 func (s *String) Type() Type      { return StringObj }
 func (s *String) Inspect() string { return s.Value }
+func (s *String) Iterator() Iterator {
+	return &stringIterator{runes: []rune(s.Value)}
+}
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
 
-	return HashKey{Value: h.Sum64()}
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
 type Array struct {
@@ -121,11 +298,19 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+func (a *Array) Iterator() Iterator {
+	return &arrayIterator{elements: a.Elements}
+}
+
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
+// HashKey identifies a hash key. Type keeps keys of different types, such
+// as 1 and "1", from ever being equal.
 type HashKey struct {
+	Type  Type
 	Value uint64
 }
 
@@ -134,8 +319,13 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash maps keys to values in insertion order. Keys are bucketed by
+// HashKey and the keys in a bucket are told apart with Equal, so keys
+// whose hashes collide never overwrite each other. The zero value is an
+// empty hash.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs   []HashPair
+	buckets map[HashKey][]int
 }
 
 func (h *Hash) Type() Type { return HashObj }
@@ -143,7 +333,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for _, v := range h.Pairs {
+	for _, v := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", v.Key.Inspect(), v.Value.Inspect()))
 	}
 
@@ -154,8 +344,508 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+func (h *Hash) Iterator() Iterator {
+	return &arrayIterator{elements: h.Keys()}
+}
+
+// Len returns the number of pairs in h.
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Get returns the value stored under key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	i, ok := h.index(key)
+	if !ok {
+		return nil, false
+	}
+	return h.pairs[i].Value, true
+}
+
+// Set stores value under key. A new key goes after all existing ones; an
+// existing key keeps its place and only its value is replaced.
+func (h *Hash) Set(key Hashable, value Object) {
+	if i, ok := h.index(key); ok {
+		h.pairs[i].Value = value
+		return
+	}
+
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+	hashed := key.HashKey()
+	h.buckets[hashed] = append(h.buckets[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// Pairs returns the pairs in h in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.pairs))
+	copy(pairs, h.pairs)
+	return pairs
+}
+
+// Keys returns the keys in h in insertion order.
+func (h *Hash) Keys() []Object {
+	keys := make([]Object, len(h.pairs))
+	for i, p := range h.pairs {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+// Values returns the values in h in insertion order.
+func (h *Hash) Values() []Object {
+	values := make([]Object, len(h.pairs))
+	for i, p := range h.pairs {
+		values[i] = p.Value
+	}
+	return values
+}
+
+// Delete removes key from h and reports whether it was present.
+func (h *Hash) Delete(key Hashable) bool {
+	i, ok := h.index(key)
+	if !ok {
+		return false
+	}
+
+	hashed := key.HashKey()
+	bucket := h.buckets[hashed]
+	for j, idx := range bucket {
+		if idx == i {
+			bucket = append(bucket[:j], bucket[j+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(h.buckets, hashed)
+	} else {
+		h.buckets[hashed] = bucket
+	}
+
+	h.pairs = append(h.pairs[:i], h.pairs[i+1:]...)
+	for _, bucket := range h.buckets {
+		for j, idx := range bucket {
+			if idx > i {
+				bucket[j] = idx - 1
+			}
+		}
+	}
+	return true
+}
+
+func (h *Hash) index(key Hashable) (int, bool) {
+	for _, i := range h.buckets[key.HashKey()] {
+		if Equal(h.pairs[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// StructType is a type declared with the struct statement. Calling it
+// constructs a StructInstance from values for its fields, in order.
+type StructType struct {
+	Name   string
+	Fields []string
+	// FieldTypes holds the type annotation of each field, or "" for a
+	// field that accepts any value.
+	FieldTypes []string
+	Methods    map[string]*Function
+}
+
+func (st *StructType) Type() Type { return StructObj }
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// StructInstance is a value of a StructType. Its Type is the name of the
+// struct, so that each struct type is distinct in error messages. That
+// name is never the Type of a built-in object.
+type StructInstance struct {
+	Struct *StructType
+	Fields []Object
+}
+
+func (si *StructInstance) Type() Type { return Type(si.Struct.Name) }
+func (si *StructInstance) Inspect() string {
+	var out bytes.Buffer
+
+	fields := make([]string, 0, len(si.Fields))
+	for i, f := range si.Fields {
+		fields = append(fields, si.Struct.Fields[i]+": "+f.Inspect())
+	}
+
+	out.WriteString(si.Struct.Name)
+	out.WriteString("(")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// Field returns the value of the field called name.
+func (si *StructInstance) Field(name string) (Object, bool) {
+	for i, f := range si.Struct.Fields {
+		if f == name {
+			return si.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// EnumType is a type declared with the enum statement.
+type EnumType struct {
+	Name     string
+	Variants []*EnumVariant
+}
+
+func (et *EnumType) Type() Type { return EnumObj }
+func (et *EnumType) Inspect() string {
+	variants := make([]string, 0, len(et.Variants))
+	for _, v := range et.Variants {
+		if len(v.Fields) == 0 {
+			variants = append(variants, v.Name)
+		} else {
+			variants = append(variants, v.Name+"("+strings.Join(v.Fields, ", ")+")")
+		}
+	}
+	return "enum " + et.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+// Variant returns the variant called name.
+func (et *EnumType) Variant(name string) (*EnumVariant, bool) {
+	for _, v := range et.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// EnumVariant is one variant of an EnumType. A variant with fields is
+// called like a function to construct an EnumValue.
+type EnumVariant struct {
+	Enum   *EnumType
+	Name   string
+	Fields []string
+}
+
+func (ev *EnumVariant) Type() Type      { return EnumVariantObj }
+func (ev *EnumVariant) Inspect() string { return ev.Enum.Name + "." + ev.Name }
+
+// EnumValue is a value of an EnumType. Like a StructInstance, its Type is
+// the name of its enum.
+type EnumValue struct {
+	Variant *EnumVariant
+	Fields  []Object
+}
+
+func (ev *EnumValue) Type() Type { return Type(ev.Variant.Enum.Name) }
+func (ev *EnumValue) Inspect() string {
+	if len(ev.Fields) == 0 {
+		return ev.Variant.Inspect()
+	}
+
+	fields := make([]string, 0, len(ev.Fields))
+	for i, f := range ev.Fields {
+		fields = append(fields, ev.Variant.Fields[i]+": "+f.Inspect())
+	}
+	return ev.Variant.Inspect() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+func (ev *EnumValue) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(ev.Variant.Enum.Name + "." + ev.Variant.Name))
+	hashElements(h, ev.Fields)
+
+	return HashKey{Type: ev.Type(), Value: h.Sum64()}
+}
+
+// Field returns the value of the field called name.
+func (ev *EnumValue) Field(name string) (Object, bool) {
+	for i, f := range ev.Variant.Fields {
+		if f == name {
+			return ev.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Tuple is an immutable, fixed-length sequence of values.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() Type { return TupleObj }
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	elements := make([]string, 0, len(t.Elements))
+	for _, e := range t.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(t.Elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+func (t *Tuple) Iterator() Iterator {
+	return &arrayIterator{elements: t.Elements}
+}
+
+// HashKey combines the hash keys of the elements. Elements that are not
+// Hashable contribute only their type; tuples holding them still work as
+// keys because keys in a Hash are ultimately compared with Equal.
+func (t *Tuple) HashKey() HashKey {
+	h := fnv.New64a()
+	hashElements(h, t.Elements)
+
+	return HashKey{Type: t.Type(), Value: h.Sum64()}
+}
+
+// hashElements hashes the elements of a tuple or enum value. A Decimal
+// holding an integer hashes as that integer, since Equal treats the two
+// as equal, and other decimals hash by their reduced value.
+func hashElements(h hash.Hash64, elements []Object) {
+	var buf [8]byte
+	for _, e := range elements {
+		if d, ok := e.(*Decimal); ok {
+			d = d.Reduce()
+			if d.Scale > 0 {
+				h.Write([]byte(d.Type()))
+				h.Write([]byte(d.Inspect()))
+				continue
+			}
+			if d.Unscaled.IsInt64() {
+				e = &Integer{Value: d.Unscaled.Int64()}
+			} else {
+				e = &BigInt{Value: d.Unscaled}
+			}
+		}
+		h.Write([]byte(e.Type()))
+		if e, ok := e.(Hashable); ok {
+			binary.LittleEndian.PutUint64(buf[:], e.HashKey().Value)
+			h.Write(buf[:])
+		}
+	}
+}
+
+// Set is a collection of distinct hashable values in insertion order. It
+// is stored as a Hash whose keys are the elements, so elements are
+// compared the same way as hash keys. The zero value is an empty set.
+type Set struct {
+	elements Hash
+}
+
+func (s *Set) Type() Type { return SetObj }
+func (s *Set) Inspect() string {
+	var out bytes.Buffer
+
+	elements := make([]string, 0, s.Len())
+	for _, e := range s.elements.Keys() {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (s *Set) Iterator() Iterator {
+	return &arrayIterator{elements: s.Elements()}
+}
+
+// Len returns the number of elements in s.
+func (s *Set) Len() int { return s.elements.Len() }
+
+// Add adds v to s unless an equal element is already present.
+func (s *Set) Add(v Hashable) {
+	if !s.Contains(v) {
+		s.elements.Set(v, v)
+	}
+}
+
+// Remove removes v from s and reports whether it was present.
+func (s *Set) Remove(v Hashable) bool { return s.elements.Delete(v) }
+
+// Contains reports whether s has an element equal to v.
+func (s *Set) Contains(v Hashable) bool {
+	_, ok := s.elements.Get(v)
+	return ok
+}
+
+// Elements returns the elements of s in insertion order.
+func (s *Set) Elements() []Object { return s.elements.Keys() }
+
+// Range is a lazy sequence of integers from Start towards End, advancing
+// by Step. End is included only when Inclusive is set.
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Inclusive bool
+}
+
+func (r *Range) Type() Type { return RangeObj }
+func (r *Range) Inspect() string {
+	op := "..<"
+	if r.Inclusive {
+		op = ".."
+	}
+	if r.Step == 1 {
+		return fmt.Sprintf("%d%s%d", r.Start, op, r.End)
+	}
+	return fmt.Sprintf("%d%s%d step %d", r.Start, op, r.End, r.Step)
+}
+
+// Len returns the number of integers in the range.
+func (r *Range) Len() int64 {
+	last := r.End
+	if r.Step > 0 {
+		if !r.Inclusive {
+			last--
+		}
+		if last < r.Start {
+			return 0
+		}
+		return int64(uint64(last-r.Start)/uint64(r.Step)) + 1
+	}
+	if !r.Inclusive {
+		last++
+	}
+	if last > r.Start {
+		return 0
+	}
+	return int64(uint64(r.Start-last)/uint64(-r.Step)) + 1
+}
+
+// At returns the i-th integer of the range. ok is false if i is out of bounds.
+func (r *Range) At(i int64) (v int64, ok bool) {
+	if i < 0 || i >= r.Len() {
+		return 0, false
+	}
+	return r.Start + i*r.Step, true
+}
+
+// Contains reports whether v is one of the integers in the range.
+func (r *Range) Contains(v int64) bool {
+	n := r.Len()
+	if n == 0 {
+		return false
+	}
+	last := r.Start + (n-1)*r.Step
+	lo, hi := r.Start, last
+	if r.Step < 0 {
+		lo, hi = last, r.Start
+	}
+	if v < lo || v > hi {
+		return false
+	}
+	return (v-r.Start)%r.Step == 0
+}
+
+func (r *Range) Iterator() Iterator {
+	return &rangeIterator{r: r, n: r.Len()}
+}
+
+type arrayIterator struct {
+	elements []Object
+	i        int
+}
+
+func (it *arrayIterator) Next() (Object, bool) {
+	if it.i >= len(it.elements) {
+		return nil, false
+	}
+	obj := it.elements[it.i]
+	it.i++
+	return obj, true
+}
+
+type stringIterator struct {
+	runes []rune
+	i     int
+}
+
+func (it *stringIterator) Next() (Object, bool) {
+	if it.i >= len(it.runes) {
+		return nil, false
+	}
+	obj := &String{Value: string(it.runes[it.i])}
+	it.i++
+	return obj, true
+}
+
+type rangeIterator struct {
+	r *Range
+	i int64
+	n int64
+}
+
+func (it *rangeIterator) Next() (Object, bool) {
+	if it.i >= it.n {
+		return nil, false
+	}
+	v, _ := it.r.At(it.i)
+	it.i++
+	return &Integer{Value: v}, true
+}
+
+// Generator produces the values yielded by one call of a generator
+// function. The function runs lazily, up to its next yield each time a
+// value is requested, so a generator can be infinite. A generator can be
+// walked only once.
+type Generator struct {
+	Name   string
+	resume func() (Object, bool)
+	close  func() Object
+}
+
+// NewGenerator returns a generator whose values are produced by resume,
+// which runs the function up to its next yield and returns the yielded
+// value, or ok false once the function has returned. close ends the
+// function early.
+func NewGenerator(name string, resume func() (obj Object, ok bool), close func() Object) *Generator {
+	return &Generator{Name: name, resume: resume, close: close}
+}
+
+func (g *Generator) Type() Type { return GeneratorObj }
+func (g *Generator) Inspect() string {
+	if g.Name == "" {
+		return "generator"
+	}
+	return "generator " + g.Name
+}
+func (g *Generator) Iterator() Iterator { return g }
+
+// Next resumes the function for its next value. g is kept reachable
+// until the function yields, as it may be finalized once unreachable.
+func (g *Generator) Next() (obj Object, ok bool) {
+	defer runtime.KeepAlive(g)
+	return g.resume()
+}
+
+// Close ends a generator that has not finished, running its cleanup, and
+// returns null or the Error the cleanup failed with.
+func (g *Generator) Close() Object {
+	defer runtime.KeepAlive(g)
+	return g.close()
+}
+
 type BuiltIn struct {
-	Fn BuiltInFunction
+	Name string
+	Fn   BuiltInFunction
+	// FrameFn is set instead of Fn by the built-ins that act on the state
+	// of the calling thread, such as its Scheduler.
+	FrameFn func(caller *Frame, args ...Object) Object
 }
 
 func (b *BuiltIn) Type() Type      { return BuiltInObj }
@@ -1,6 +1,13 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/yuzuy/yoru/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 	foo1 := &String{Value: "foo"}
@@ -19,3 +26,306 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different value have same hash keys")
 	}
 }
+
+// collidingKey is a hash key whose HashKey is the same for every value.
+type collidingKey struct {
+	name string
+}
+
+func (k *collidingKey) Type() Type       { return "COLLIDING_KEY" }
+func (k *collidingKey) Inspect() string  { return k.name }
+func (k *collidingKey) HashKey() HashKey { return HashKey{Type: k.Type(), Value: 42} }
+
+func TestHashCollisions(t *testing.T) {
+	a := &collidingKey{name: "a"}
+	b := &collidingKey{name: "b"}
+	c := &collidingKey{name: "c"}
+	if a.HashKey() != b.HashKey() {
+		t.Fatalf("keys do not collide")
+	}
+
+	h := &Hash{}
+	h.Set(a, &Integer{Value: 1})
+	h.Set(b, &Integer{Value: 2})
+	h.Set(a, &Integer{Value: 3})
+
+	if h.Len() != 2 {
+		t.Fatalf("hash has wrong length. expected=2, got=%d", h.Len())
+	}
+	for _, tt := range []struct {
+		key      Hashable
+		expected int64
+	}{{a, 3}, {b, 2}} {
+		v, ok := h.Get(tt.key)
+		if !ok {
+			t.Fatalf("no value for key %s", tt.key.Inspect())
+		}
+		if v.(*Integer).Value != tt.expected {
+			t.Errorf("wrong value for key %s. expected=%d, got=%d", tt.key.Inspect(), tt.expected, v.(*Integer).Value)
+		}
+	}
+	if _, ok := h.Get(c); ok {
+		t.Errorf("found value for key c that was never set")
+	}
+	if len(h.Pairs()) != 2 {
+		t.Errorf("Pairs has wrong length. expected=2, got=%d", len(h.Pairs()))
+	}
+
+	other := &Hash{}
+	other.Set(b, &Integer{Value: 2})
+	other.Set(c, &Integer{Value: 3})
+	if Equal(h, other) {
+		t.Errorf("hashes with colliding but different keys are equal")
+	}
+}
+
+func TestHashDelete(t *testing.T) {
+	a := &collidingKey{name: "a"}
+	b := &collidingKey{name: "b"}
+	c := &String{Value: "c"}
+
+	h := &Hash{}
+	h.Set(a, &Integer{Value: 1})
+	h.Set(c, &Integer{Value: 2})
+	h.Set(b, &Integer{Value: 3})
+
+	if !h.Delete(a) {
+		t.Fatalf("Delete(a) reported missing key")
+	}
+	if h.Delete(a) {
+		t.Errorf("Delete(a) twice reported present key")
+	}
+	if h.Len() != 2 {
+		t.Fatalf("hash has wrong length. expected=2, got=%d", h.Len())
+	}
+	if _, ok := h.Get(a); ok {
+		t.Errorf("deleted key a still found")
+	}
+	for _, tt := range []struct {
+		key      Hashable
+		expected int64
+	}{{c, 2}, {b, 3}} {
+		v, ok := h.Get(tt.key)
+		if !ok || v.(*Integer).Value != tt.expected {
+			t.Errorf("wrong value for key %s after delete. got=%v", tt.key.Inspect(), v)
+		}
+	}
+	if got := h.Inspect(); got != "{c: 2, b: 3}" {
+		t.Errorf("wrong order after delete. got=%s", got)
+	}
+}
+
+func TestHashGetAllocs(t *testing.T) {
+	h := &Hash{}
+	for i := int64(0); i < 100; i++ {
+		h.Set(&Integer{Value: i}, &Integer{Value: i})
+	}
+	key := &Integer{Value: 42}
+
+	allocs := testing.AllocsPerRun(100, func() { h.Get(key) })
+	if allocs != 0 {
+		t.Errorf("integer key lookup allocates. got=%v allocs", allocs)
+	}
+}
+
+func BenchmarkHashGet(b *testing.B) {
+	keys := map[string]func(i int64) Hashable{
+		"integer": func(i int64) Hashable { return &Integer{Value: i} },
+		"string":  func(i int64) Hashable { return &String{Value: fmt.Sprintf("key%d", i)} },
+	}
+	for name, key := range keys {
+		b.Run(name, func(b *testing.B) {
+			h := &Hash{}
+			for i := int64(0); i < 1000; i++ {
+				h.Set(key(i), &Integer{Value: i})
+			}
+			k := key(500)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.Get(k)
+			}
+		})
+	}
+}
+
+func TestHashKeyTypes(t *testing.T) {
+	tests := []struct {
+		a, b  Hashable
+		equal bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Integer{Value: 2}, false},
+		{&Integer{Value: 1}, &String{Value: "1"}, false},
+		{&Integer{Value: 1}, &Boolean{Value: true}, false},
+		{&Integer{Value: 0}, &Null{}, false},
+		{&Boolean{Value: false}, &Null{}, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Null{}, &Null{}, true},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}, &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}, true},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}, &String{Value: "18446744073709551616"}, false},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, &Tuple{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, true},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, &Tuple{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}, false},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}}}, &Integer{Value: 1}, false},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}}}, &Tuple{Elements: []Object{&String{Value: "1"}}}, false},
+		{&Tuple{Elements: []Object{&Integer{Value: 1}}}, &Tuple{Elements: []Object{&Decimal{Unscaled: big.NewInt(100), Scale: 2}}}, true},
+		{&Tuple{Elements: []Object{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 64)}}}, &Tuple{Elements: []Object{NewDecimalFromInt(new(big.Int).Lsh(big.NewInt(1), 64))}}, true},
+		{&Tuple{Elements: []Object{&Decimal{Unscaled: big.NewInt(15), Scale: 1}}}, &Tuple{Elements: []Object{&Decimal{Unscaled: big.NewInt(150), Scale: 2}}}, true},
+		{&Tuple{Elements: []Object{&Decimal{Unscaled: big.NewInt(15), Scale: 1}}}, &Tuple{Elements: []Object{&Decimal{Unscaled: big.NewInt(16), Scale: 1}}}, false},
+	}
+
+	for i, tt := range tests {
+		if got := tt.a.HashKey() == tt.b.HashKey(); got != tt.equal {
+			t.Errorf("tests[%d] - HashKey equality of %s and %s wrong. expected=%t, got=%t",
+				i, tt.a.(Object).Inspect(), tt.b.(Object).Inspect(), tt.equal, got)
+		}
+	}
+}
+
+func TestDecimalRescale(t *testing.T) {
+	tests := []struct {
+		input    string
+		mode     RoundingMode
+		expected string
+	}{
+		{"2.5", RoundHalfEven, "2"},
+		{"3.5", RoundHalfEven, "4"},
+		{"-2.5", RoundHalfEven, "-2"},
+		{"2.5", RoundHalfUp, "3"},
+		{"-2.5", RoundHalfUp, "-3"},
+		{"2.5", RoundHalfDown, "2"},
+		{"2.51", RoundHalfDown, "3"},
+		{"2.1", RoundUp, "3"},
+		{"-2.1", RoundUp, "-3"},
+		{"2.9", RoundDown, "2"},
+		{"-2.9", RoundDown, "-2"},
+		{"-2.1", RoundCeiling, "-2"},
+		{"2.1", RoundCeiling, "3"},
+		{"-2.1", RoundFloor, "-3"},
+		{"2.9", RoundFloor, "2"},
+		{"2.0", RoundUp, "2"},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.input)
+		if err != nil {
+			t.Fatalf("ParseDecimal(%q) returned error: %s", tt.input, err)
+		}
+		if got := d.Rescale(0, tt.mode).Inspect(); got != tt.expected {
+			t.Errorf("%s rounded with mode %d: expected=%s, got=%s", tt.input, tt.mode, tt.expected, got)
+		}
+	}
+}
+
+func TestDecimalInspect(t *testing.T) {
+	tests := []struct {
+		d        *Decimal
+		expected string
+	}{
+		{&Decimal{Unscaled: big.NewInt(1250), Scale: 2}, "12.50"},
+		{&Decimal{Unscaled: big.NewInt(5), Scale: 3}, "0.005"},
+		{&Decimal{Unscaled: big.NewInt(-5), Scale: 1}, "-0.5"},
+		{&Decimal{Unscaled: big.NewInt(0), Scale: 2}, "0.00"},
+		{&Decimal{Unscaled: big.NewInt(42), Scale: 0}, "42"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.Inspect(); got != tt.expected {
+			t.Errorf("wrong Inspect. expected=%s, got=%s", tt.expected, got)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     Object
+		expected int
+		ok       bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 2}, -1, true},
+		{&BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}, &Integer{Value: 2}, 1, true},
+		{&Decimal{Unscaled: big.NewInt(150), Scale: 2}, &Integer{Value: 1}, 1, true},
+		{&Decimal{Unscaled: big.NewInt(10), Scale: 1}, &Integer{Value: 1}, 0, true},
+		{&String{Value: "a"}, &String{Value: "b"}, -1, true},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Array{Elements: []Object{&Integer{Value: 1}}}, 0, true},
+		{&Array{Elements: []Object{}}, &Array{Elements: []Object{&Integer{Value: 1}}}, -1, true},
+		{&String{Value: "a"}, &Integer{Value: 1}, 0, false},
+		{&Boolean{Value: true}, &Boolean{Value: false}, 0, false},
+	}
+
+	for i, tt := range tests {
+		got, ok := Compare(tt.a, tt.b)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("tests[%d] - Compare(%s, %s) wrong. expected=(%d, %t), got=(%d, %t)",
+				i, tt.a.Inspect(), tt.b.Inspect(), tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		r        *Range
+		expected []int64
+	}{
+		{&Range{Start: 1, End: 5, Step: 1, Inclusive: true}, []int64{1, 2, 3, 4, 5}},
+		{&Range{Start: 1, End: 5, Step: 1}, []int64{1, 2, 3, 4}},
+		{&Range{Start: 0, End: 10, Step: 4, Inclusive: true}, []int64{0, 4, 8}},
+		{&Range{Start: 5, End: 1, Step: -2}, []int64{5, 3}},
+		{&Range{Start: 5, End: 1, Step: 1}, []int64{}},
+	}
+
+	for _, tt := range tests {
+		if tt.r.Len() != int64(len(tt.expected)) {
+			t.Errorf("%s: wrong length. expected=%d, got=%d", tt.r.Inspect(), len(tt.expected), tt.r.Len())
+			continue
+		}
+
+		iter := tt.r.Iterator()
+		for i, want := range tt.expected {
+			obj, ok := iter.Next()
+			if !ok {
+				t.Fatalf("%s: iterator exhausted at %d", tt.r.Inspect(), i)
+			}
+			if got := obj.(*Integer).Value; got != want {
+				t.Errorf("%s: element %d wrong. expected=%d, got=%d", tt.r.Inspect(), i, want, got)
+			}
+			if !tt.r.Contains(want) {
+				t.Errorf("%s: does not contain %d", tt.r.Inspect(), want)
+			}
+		}
+		if _, ok := iter.Next(); ok {
+			t.Errorf("%s: iterator not exhausted", tt.r.Inspect())
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	err := &Error{
+		Message: "boom",
+		Trace: []TraceEntry{
+			{Function: "inner", Pos: token.Position{Line: 3, Column: 7}},
+			{Function: "", Pos: token.Position{Line: 9, Column: 2}},
+		},
+	}
+
+	expected := "\tat inner (called at 3:7)\n\tat <anonymous> (called at 9:2)\n"
+	if err.StackTrace() != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, err.StackTrace())
+	}
+}
+
+func TestErrorStackTraceElision(t *testing.T) {
+	err := &Error{Message: "boom"}
+	for i := 0; i < 25; i++ {
+		err.Trace = append(err.Trace, TraceEntry{Function: "f", Pos: token.Position{Line: 1, Column: 1}})
+	}
+
+	lines := strings.Split(strings.TrimSuffix(err.StackTrace(), "\n"), "\n")
+	if len(lines) != maxTraceLines+1 {
+		t.Fatalf("wrong number of lines. expected=%d, got=%d", maxTraceLines+1, len(lines))
+	}
+	if lines[maxTraceLines/2] != "\t... 5 more calls ..." {
+		t.Errorf("wrong elision line. got=%q", lines[maxTraceLines/2])
+	}
+}
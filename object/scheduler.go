@@ -0,0 +1,291 @@
+package object
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrClosedChannel is reported by a send on a closed channel.
+	ErrClosedChannel = errors.New("send on closed channel")
+	// ErrDeadlock is reported by the blocked operations of a program in
+	// which every thread is blocked, as none of them can wake the others.
+	ErrDeadlock = errors.New("deadlock: all tasks are blocked")
+	// ErrCancelled is reported to the tasks still running once the Eval
+	// that started them has returned, and by operations that would block
+	// while no Eval is running.
+	ErrCancelled = errors.New("task cancelled: the program has ended")
+	// ErrForeign is reported by an operation on a channel or task that
+	// belongs to the Scheduler of another environment.
+	ErrForeign = errors.New("channel or task belongs to another environment")
+)
+
+// Scheduler runs the threads evaluating the code of one top-level
+// Environment: the Eval calls on it, which are its roots, and the tasks
+// they spawn. A generator body runs as part of the thread that resumes it.
+// The channels and tasks made in an environment belong to its Scheduler,
+// and a single lock guards them all, so that a thread that cannot proceed
+// checks for deadlock in the same step as it blocks.
+//
+// Threads live no longer than the Eval calls that started them. Once the
+// last running Eval returns, the tasks still running are cancelled: their
+// blocked operations and those they try next fail with ErrCancelled, as
+// do their loop iterations, and End waits for them to stop.
+// The generators still suspended are then closed, running their cleanup.
+type Scheduler struct {
+	mu sync.Mutex
+	// idle is signalled when End has finished shutting down.
+	idle    *sync.Cond
+	roots   int
+	running int
+	ending  bool
+	blocked map[*waiter]bool
+	// generators holds the close functions of the generators that have
+	// started and not finished.
+	generators map[*trackedGenerator]bool
+
+	// stopping is set while End cancels the tasks, and stop is closed at
+	// the same time to wake the tasks that are sleeping.
+	stopping int32
+	stop     chan struct{}
+	tasks    sync.WaitGroup
+}
+
+type trackedGenerator struct {
+	close func() Object
+}
+
+func NewScheduler() *Scheduler {
+	s := &Scheduler{
+		blocked:    make(map[*waiter]bool),
+		generators: make(map[*trackedGenerator]bool),
+		stop:       make(chan struct{}),
+	}
+	s.idle = sync.NewCond(&s.mu)
+	return s
+}
+
+// Begin records that an Eval call starts running. If the last one is
+// still shutting down, Begin waits for it to finish.
+func (s *Scheduler) Begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.ending {
+		s.idle.Wait()
+	}
+	s.roots++
+	s.running++
+}
+
+// End records that an Eval call has returned. If it was the last one
+// running, End cancels the remaining tasks, waits for them to stop and
+// closes the suspended generators. The errors their cleanup fails with
+// are dropped, as the Eval has already produced its result.
+func (s *Scheduler) End() {
+	s.mu.Lock()
+	s.roots--
+	s.running--
+	if s.roots > 0 {
+		s.checkDeadlock()
+		s.mu.Unlock()
+		return
+	}
+
+	s.ending = true
+	atomic.StoreInt32(&s.stopping, 1)
+	close(s.stop)
+	s.wakeAll(ErrCancelled)
+	s.mu.Unlock()
+
+	s.tasks.Wait()
+
+	s.mu.Lock()
+	atomic.StoreInt32(&s.stopping, 0)
+	s.stop = make(chan struct{})
+	// closing a generator runs its code, which may start others
+	for len(s.generators) > 0 {
+		for g := range s.generators {
+			delete(s.generators, g)
+			s.mu.Unlock()
+			g.close()
+			s.mu.Lock()
+			break
+		}
+	}
+	s.ending = false
+	s.idle.Broadcast()
+	s.mu.Unlock()
+}
+
+// StartTask records that a task is about to start, before its goroutine
+// does, so that its starter cannot be found deadlocked in the meantime.
+// It fails with ErrCancelled if no Eval is running.
+func (s *Scheduler) StartTask() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.roots == 0 {
+		return ErrCancelled
+	}
+	s.running++
+	s.tasks.Add(1)
+	return nil
+}
+
+// EndTask records that a task started by StartTask has finished.
+func (s *Scheduler) EndTask() {
+	s.mu.Lock()
+	s.running--
+	s.checkDeadlock()
+	s.mu.Unlock()
+	s.tasks.Done()
+}
+
+// Stopping reports whether the tasks are being cancelled.
+func (s *Scheduler) Stopping() bool {
+	return atomic.LoadInt32(&s.stopping) != 0
+}
+
+// Stop returns a channel that is closed when the tasks are cancelled.
+func (s *Scheduler) Stop() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop
+}
+
+// TrackGenerator records close, the function closing a generator that
+// has started, for End to call if the generator is still suspended then.
+// untrack must be called once the generator has finished.
+func (s *Scheduler) TrackGenerator(close func() Object) (untrack func()) {
+	g := &trackedGenerator{close: close}
+	s.mu.Lock()
+	s.generators[g] = true
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		delete(s.generators, g)
+		s.mu.Unlock()
+	}
+}
+
+// waiter is a blocked operation. It is woken once another thread has
+// completed it, or with err set.
+type waiter struct {
+	wake   chan struct{}
+	done   bool
+	chosen int
+	value  Object
+	ok     bool
+	err    error
+}
+
+func newWaiter() *waiter {
+	return &waiter{wake: make(chan struct{}, 1)}
+}
+
+// waitFor records that the current thread waits for w, and reports
+// deadlock if no thread is left to wake it. s.mu must be held; it is
+// released while waiting.
+func (s *Scheduler) waitFor(w *waiter) {
+	s.blocked[w] = true
+	s.running--
+	s.checkDeadlock()
+
+	s.mu.Unlock()
+	<-w.wake
+	s.mu.Lock()
+}
+
+// canBlock returns the error an operation that has to wait fails with
+// right away, or nil. s.mu must be held.
+func (s *Scheduler) canBlock() error {
+	if s.roots == 0 || s.Stopping() {
+		return ErrCancelled
+	}
+	return nil
+}
+
+// complete completes w and counts its thread as running again. s.mu must
+// be held.
+func (s *Scheduler) complete(w *waiter, chosen int, value Object, ok bool, err error) {
+	w.done = true
+	w.chosen, w.value, w.ok, w.err = chosen, value, ok, err
+	delete(s.blocked, w)
+	s.running++
+	w.wake <- struct{}{}
+}
+
+func (s *Scheduler) checkDeadlock() {
+	if s.running == 0 && s.roots > 0 {
+		s.wakeAll(ErrDeadlock)
+	}
+}
+
+func (s *Scheduler) wakeAll(err error) {
+	for w := range s.blocked {
+		s.complete(w, -1, nil, false, err)
+	}
+}
+
+// SelectCase is a send of Value on Chan, or a receive from it.
+type SelectCase struct {
+	Chan  *Channel
+	Send  bool
+	Value Object
+}
+
+// Select performs one of cases, chosen at random among those that are
+// ready, and returns its index, along with the value received and whether
+// it was sent for a receive. If none is ready, it returns -1 unless block
+// is set, in which case it waits until one is. It fails with
+// ErrClosedChannel if the chosen case sends on a closed channel, with
+// ErrDeadlock if every thread is blocked, with ErrCancelled if it has to
+// wait while the tasks are cancelled, and with ErrForeign if a channel
+// belongs to another Scheduler.
+func (s *Scheduler) Select(cases []SelectCase, block bool) (chosen int, recv Object, recvOK bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range cases {
+		if c.Chan.sched != s {
+			return -1, nil, false, ErrForeign
+		}
+	}
+	for _, i := range rand.Perm(len(cases)) {
+		c := cases[i]
+		if c.Send {
+			ok, err := c.Chan.trySend(c.Value)
+			if ok || err != nil {
+				return i, nil, false, err
+			}
+			continue
+		}
+		if v, ok, ready := c.Chan.tryReceive(); ready {
+			return i, v, ok, nil
+		}
+	}
+	if !block {
+		return -1, nil, false, nil
+	}
+	if err := s.canBlock(); err != nil {
+		return -1, nil, false, err
+	}
+
+	w := newWaiter()
+	for i, c := range cases {
+		p := pending{w: w, index: i, value: c.Value}
+		if c.Send {
+			c.Chan.sendq = append(c.Chan.sendq, p)
+		} else {
+			c.Chan.recvq = append(c.Chan.recvq, p)
+		}
+	}
+	s.waitFor(w)
+	for _, c := range cases {
+		c.Chan.sendq = remove(c.Chan.sendq, w)
+		c.Chan.recvq = remove(c.Chan.recvq, w)
+	}
+	return w.chosen, w.value, w.ok, w.err
+}
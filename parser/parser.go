@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/yuzuy/yoru/ast"
@@ -13,33 +14,57 @@ import (
 const (
 	_ int = iota
 	LowSet
+	Assign      // =
 	Equals      // ==
 	LessGreater // > or <
+	Range       // ..
+	BitOr       // |
+	BitXor      // ^
+	BitAnd      // &
+	Shift       // << or >>
 	Sum         // +
 	Product     // *
 	Prefix      // !x or -x
+	Power       // **
+	Postfix     // x?
 	Call        // function
 	Index       // array[idx]
 )
 
 var precedences = map[token.Type]int{
-	token.EQ:       Equals,
-	token.NotEQ:    Equals,
-	token.LT:       LessGreater,
-	token.GT:       LessGreater,
-	token.Plus:     Sum,
-	token.Minus:    Sum,
-	token.Slash:    Product,
-	token.Asterisk: Product,
-	token.Mod:      Product,
-	token.Lparen:   Call,
-	token.LBracket: Index,
+	token.Assign:    Assign,
+	token.EQ:        Equals,
+	token.NotEQ:     Equals,
+	token.LT:        LessGreater,
+	token.GT:        LessGreater,
+	token.In:        LessGreater,
+	token.DotDot:    Range,
+	token.DotDotLT:  Range,
+	token.Plus:      Sum,
+	token.Minus:     Sum,
+	token.Slash:     Product,
+	token.Asterisk:  Product,
+	token.Mod:       Product,
+	token.Pipe:      BitOr,
+	token.Caret:     BitXor,
+	token.Ampersand: BitAnd,
+	token.LShift:    Shift,
+	token.RShift:    Shift,
+	token.Power:     Power,
+	token.Question:  Postfix,
+	token.Lparen:    Call,
+	token.LBracket:  Index,
+	token.Dot:       Index,
 }
 
 type Parser struct {
 	l      *lexer.Lexer
 	errors []error
 
+	// functions are the function literals whose bodies are being parsed,
+	// innermost last.
+	functions []*ast.FunctionLiteral
+
 	curToken  token.Token
 	peekToken token.Token
 
@@ -56,17 +81,21 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.Ident, p.parseIdentifier)
 	p.registerPrefix(token.Int, p.parseIntegerLiteral)
+	p.registerPrefix(token.Decimal, p.parseDecimalLiteral)
 	p.registerPrefix(token.Bang, p.parsePrefixExpression)
 	p.registerPrefix(token.Minus, p.parsePrefixExpression)
+	p.registerPrefix(token.Tilde, p.parsePrefixExpression)
 	p.registerPrefix(token.True, p.parseBoolean)
 	p.registerPrefix(token.False, p.parseBoolean)
 	p.registerPrefix(token.Lparen, p.parseGroupedExpression)
 	p.registerPrefix(token.LBracket, p.parseArrayLiteral)
 	p.registerPrefix(token.Lbrace, p.parseHashLiteral)
+	p.registerPrefix(token.SetBrace, p.parseSetLiteral)
 	p.registerPrefix(token.If, p.parseIfExpression)
 	p.registerPrefix(token.Function, p.parseFunctionLiteral)
 	p.registerPrefix(token.String, p.parseStringLiteral)
 	p.registerPrefix(token.Null, p.parseNull)
+	p.registerPrefix(token.Spawn, p.parseSpawnExpression)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfix(token.Plus, p.parseInfixExpression)
@@ -74,12 +103,24 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.Slash, p.parseInfixExpression)
 	p.registerInfix(token.Asterisk, p.parseInfixExpression)
 	p.registerInfix(token.Mod, p.parseInfixExpression)
+	p.registerInfix(token.Power, p.parseInfixExpression)
+	p.registerInfix(token.Ampersand, p.parseInfixExpression)
+	p.registerInfix(token.Pipe, p.parseInfixExpression)
+	p.registerInfix(token.Caret, p.parseInfixExpression)
+	p.registerInfix(token.LShift, p.parseInfixExpression)
+	p.registerInfix(token.RShift, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NotEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.Assign, p.parseAssignExpression)
+	p.registerInfix(token.In, p.parseInfixExpression)
+	p.registerInfix(token.DotDot, p.parseRangeLiteral)
+	p.registerInfix(token.DotDotLT, p.parseRangeLiteral)
+	p.registerInfix(token.Question, p.parsePostfixExpression)
 	p.registerInfix(token.Lparen, p.parseCallExpression)
 	p.registerInfix(token.LBracket, p.parseIndexExpression)
+	p.registerInfix(token.Dot, p.parseMemberExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -115,6 +156,22 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseReturnStatement()
 	case token.Switch:
 		return p.parseSwitchStatement()
+	case token.For:
+		return p.parseForStatement()
+	case token.Defer:
+		return p.parseDeferStatement()
+	case token.Try:
+		return p.parseTryStatement()
+	case token.Throw:
+		return p.parseThrowStatement()
+	case token.Yield:
+		return p.parseYieldStatement()
+	case token.Select:
+		return p.parseSelectStatement()
+	case token.Struct:
+		return p.parseStructStatement()
+	case token.Enum:
+		return p.parseEnumStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -123,11 +180,25 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.Ident) {
-		return nil
-	}
+	if p.peekTokenIs(token.Lparen) {
+		p.nextToken()
+		stmt.Names = p.parseLetNames()
+		if stmt.Names == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.Ident) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTokenIs(token.Colon) {
+			p.nextToken()
+			if stmt.Name.Type = p.parseTypeName(); stmt.Name.Type == nil {
+				return nil
+			}
+		}
+	}
 
 	if !p.expectPeek(token.Assign) {
 		return nil
@@ -137,6 +208,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LowSet)
 
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.Semicolon) {
 		p.nextToken()
 	}
@@ -144,6 +219,30 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parseLetNames parses a parenthesized list of one or more names, such as
+// the (a, b) of let (a, b) = t or the fields of an enum variant.
+func (p *Parser) parseLetNames() []*ast.Identifier {
+	var names []*ast.Identifier
+
+	for {
+		if !p.expectPeek(token.Ident) {
+			return nil
+		}
+		names = append(names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.Rparen) {
+		return nil
+	}
+
+	return names
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
@@ -158,6 +257,273 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	call, ok := p.parseExpression(LowSet).(*ast.CallExpression)
+	if !ok {
+		p.errors = append(p.errors, errors.New("expression in defer must be function call"))
+		return nil
+	}
+	stmt.Call = call
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.Lbrace) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.Catch) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.Lparen) {
+			p.nextToken()
+			if !p.expectPeek(token.Ident) {
+				return nil
+			}
+			stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.Rparen) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.Lbrace) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.Finally) {
+		p.nextToken()
+
+		if !p.expectPeek(token.Lbrace) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errors = append(p.errors, errors.New("try requires a catch or finally block"))
+		return nil
+	}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LowSet)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{Token: p.curToken}
+
+	if len(p.functions) == 0 {
+		p.errors = append(p.errors, errors.New("yield outside function"))
+		return nil
+	}
+	p.functions[len(p.functions)-1].Generator = true
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LowSet)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.Ident) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.Lbrace) {
+		return nil
+	}
+
+	members := make(map[string]bool)
+	for !p.peekTokenIs(token.Rbrace) {
+		p.nextToken()
+
+		var name string
+		switch p.curToken.Type {
+		case token.Comma, token.Semicolon:
+			continue
+		case token.Ident:
+			field := p.parseParameter()
+			stmt.Fields = append(stmt.Fields, field)
+			name = field.Value
+		case token.Function:
+			method := p.parseMethod()
+			if method == nil {
+				return nil
+			}
+			stmt.Methods = append(stmt.Methods, method)
+			name = method.Name
+		default:
+			msg := fmt.Sprintf("unexpected %s in struct %s", p.curToken.Literal, stmt.Name)
+			p.errors = append(p.errors, errors.New(msg))
+			return nil
+		}
+
+		if members[name] {
+			msg := fmt.Sprintf("duplicate member %s in struct %s", name, stmt.Name)
+			p.errors = append(p.errors, errors.New(msg))
+			return nil
+		}
+		members[name] = true
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.Ident) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.Lbrace) {
+		return nil
+	}
+
+	variants := make(map[string]bool)
+	for !p.peekTokenIs(token.Rbrace) {
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.Comma, token.Semicolon:
+			continue
+		case token.Ident:
+		default:
+			msg := fmt.Sprintf("unexpected %s in enum %s", p.curToken.Literal, stmt.Name)
+			p.errors = append(p.errors, errors.New(msg))
+			return nil
+		}
+
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if p.peekTokenIs(token.Lparen) {
+			p.nextToken()
+			variant.Fields = p.parseLetNames()
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+
+		if variants[variant.Name.Value] {
+			msg := fmt.Sprintf("duplicate variant %s in enum %s", variant.Name, stmt.Name)
+			p.errors = append(p.errors, errors.New(msg))
+			return nil
+		}
+		variants[variant.Name.Value] = true
+		stmt.Variants = append(stmt.Variants, variant)
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// overloadableOperators are the infix operators a struct can define as a
+// method, as in fn +(other) { ... }. Indexing is defined with fn [](i).
+var overloadableOperators = map[token.Type]bool{
+	token.Plus:      true,
+	token.Minus:     true,
+	token.Asterisk:  true,
+	token.Slash:     true,
+	token.Mod:       true,
+	token.Power:     true,
+	token.Ampersand: true,
+	token.Pipe:      true,
+	token.Caret:     true,
+	token.LShift:    true,
+	token.RShift:    true,
+	token.LT:        true,
+	token.GT:        true,
+	token.EQ:        true,
+	token.NotEQ:     true,
+}
+
+// parseMethod parses a method declared in a struct as fn name(params) { body }.
+// The name may also be an overloadable operator or [].
+func (p *Parser) parseMethod() *ast.FunctionLiteral {
+	method := &ast.FunctionLiteral{Token: p.curToken}
+
+	p.nextToken()
+	switch {
+	case p.curTokenIs(token.Ident), overloadableOperators[p.curToken.Type]:
+		method.Name = p.curToken.Literal
+	case p.curTokenIs(token.LBracket):
+		if !p.expectPeek(token.RBracket) {
+			return nil
+		}
+		method.Name = "[]"
+	default:
+		msg := fmt.Sprintf("expected method name, got %s instead", p.curToken.Literal)
+		p.errors = append(p.errors, errors.New(msg))
+		return nil
+	}
+
+	if !p.expectPeek(token.Lparen) {
+		return nil
+	}
+	method.Parameters = p.parseFunctionParameters()
+
+	if p.peekTokenIs(token.ThinArrow) {
+		p.nextToken()
+		if method.ReturnType = p.parseTypeName(); method.ReturnType == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.Lbrace) {
+		return nil
+	}
+	method.Body = p.parseFunctionBody(method)
+
+	return method
+}
+
 func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
 	stmt := &ast.SwitchStatement{
 		Token: p.curToken,
@@ -198,19 +564,7 @@ func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
 			if !p.curTokenIs(token.Colon) {
 				return nil
 			}
-			p.nextToken()
-			var stmts []ast.Statement
-			for {
-				stmt := p.parseStatement()
-				if stmt != nil {
-					stmts = append(stmts, stmt)
-				}
-				p.nextToken()
-				if p.curTokenIs(token.Case) || p.curTokenIs(token.Default) || p.curTokenIs(token.Rbrace) {
-					break
-				}
-			}
-			c.Block = stmts
+			c.Block = p.parseCaseBlock()
 			stmt.Cases[order] = c
 			order++
 		case token.Default:
@@ -218,20 +572,67 @@ func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
 			if !p.curTokenIs(token.Colon) {
 				return nil
 			}
+			stmt.Default = p.parseCaseBlock()
+		default:
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseCaseBlock parses the statements of a switch or select case up to
+// the next case, default or closing brace. The current token must be the
+// colon before them.
+func (p *Parser) parseCaseBlock() []ast.Statement {
+	stmts := []ast.Statement{}
+
+	p.nextToken()
+	for !p.curTokenIs(token.Case) && !p.curTokenIs(token.Default) &&
+		!p.curTokenIs(token.Rbrace) && !p.curTokenIs(token.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+		p.nextToken()
+	}
+
+	return stmts
+}
+
+func (p *Parser) parseSelectStatement() *ast.SelectStatement {
+	stmt := &ast.SelectStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.Lbrace) {
+		return nil
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(token.Rbrace) {
+		switch p.curToken.Type {
+		case token.Case:
+			c := &ast.SelectCase{Token: p.curToken}
 			p.nextToken()
-			var stmts []ast.Statement
-			for {
-				stmt := p.parseStatement()
-				if stmt != nil {
-					stmts = append(stmts, stmt)
-				}
-				p.nextToken()
-				if p.curTokenIs(token.Case) || p.curTokenIs(token.Default) || p.curTokenIs(token.Rbrace) {
-					break
-				}
+			if !p.parseSelectCommunication(c) {
+				return nil
+			}
+			if !p.expectPeek(token.Colon) {
+				return nil
+			}
+			c.Block = p.parseCaseBlock()
+			stmt.Cases = append(stmt.Cases, c)
+		case token.Default:
+			if !p.expectPeek(token.Colon) {
+				return nil
 			}
-			stmt.Default = stmts
+			stmt.Default = p.parseCaseBlock()
 		default:
+			msg := fmt.Sprintf("expected case or default in select, got %s instead", p.curToken.Type)
+			p.errors = append(p.errors, errors.New(msg))
 			return nil
 		}
 	}
@@ -243,6 +644,65 @@ func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
 	return stmt
 }
 
+// parseSelectCommunication parses the send(ch, v), receive(ch) or
+// name = receive(ch) of a select case into c.
+func (p *Parser) parseSelectCommunication(c *ast.SelectCase) bool {
+	exp := p.parseExpression(LowSet)
+	if assign, ok := exp.(*ast.AssignExpression); ok {
+		c.Name = assign.Name
+		exp = assign.Value
+	}
+
+	call, ok := exp.(*ast.CallExpression)
+	if ok {
+		c.Call = call
+		fn, _ := call.Function.(*ast.Identifier)
+		switch {
+		case fn == nil:
+		case fn.Value == "receive" && len(call.Arguments) == 1:
+			return true
+		case fn.Value == "send" && len(call.Arguments) == 2 && c.Name == nil:
+			c.Send = true
+			return true
+		}
+	}
+
+	p.errors = append(p.errors, errors.New("select case must be send(ch, v), receive(ch) or name = receive(ch)"))
+	return false
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.Lparen) {
+		return nil
+	}
+	if !p.expectPeek(token.Ident) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.In) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LowSet)
+
+	if !p.expectPeek(token.Rparen) {
+		return nil
+	}
+	if !p.expectPeek(token.Lbrace) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -321,7 +781,29 @@ func (p *Parser) registerInfix(tokenType token.Type, fn infixParseFn) {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.Arrow) {
+		p.nextToken()
+		return p.parseArrowFunction([]*ast.Identifier{ident})
+	}
+
+	return ident
+}
+
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	exp := &ast.SpawnExpression{Token: p.curToken}
+
+	p.nextToken()
+
+	call, ok := p.parseExpression(Prefix).(*ast.CallExpression)
+	if !ok {
+		p.errors = append(p.errors, errors.New("expression in spawn must be function call"))
+		return nil
+	}
+	exp.Call = call
+
+	return exp
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
@@ -333,11 +815,67 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	lit.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.ThinArrow) {
+		p.nextToken()
+		if lit.ReturnType = p.parseTypeName(); lit.ReturnType == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.Lbrace) {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody(lit)
+
+	return lit
+}
+
+// parseFunctionBody parses the block body of lit, noting on lit whether
+// it yields. The current token must be the opening brace.
+func (p *Parser) parseFunctionBody(lit *ast.FunctionLiteral) *ast.BlockStatement {
+	p.functions = append(p.functions, lit)
+	defer func() { p.functions = p.functions[:len(p.functions)-1] }()
+
+	return p.parseBlockStatement()
+}
+
+// parseTypeName parses the type of an annotation, such as the int of
+// x: int or -> int. The current token must be the colon or arrow.
+func (p *Parser) parseTypeName() *ast.TypeName {
+	p.nextToken()
+
+	switch p.curToken.Type {
+	case token.Ident, token.Function, token.Null:
+		return &ast.TypeName{Token: p.curToken, Name: p.curToken.Literal}
+	default:
+		msg := fmt.Sprintf("expected type name, got %s instead", p.curToken.Literal)
+		p.errors = append(p.errors, errors.New(msg))
+		return nil
+	}
+}
+
+// parseArrowFunction parses `params => body` into the same FunctionLiteral
+// as `fn(params) { body }`. The current token must be the arrow.
+func (p *Parser) parseArrowFunction(params []*ast.Identifier) ast.Expression {
+	lit := &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.Function, Literal: "fn"},
+		Parameters: params,
+	}
+
+	if p.peekTokenIs(token.Lbrace) {
+		p.nextToken()
+		lit.Body = p.parseFunctionBody(lit)
+		return lit
+	}
+
+	p.nextToken()
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LowSet)
+	lit.Body = &ast.BlockStatement{
+		Token:      stmt.Token,
+		Statements: []ast.Statement{stmt},
+	}
 
 	return lit
 }
@@ -351,15 +889,12 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 
 	p.nextToken()
-
-	i := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	is = append(is, i)
+	is = append(is, p.parseParameter())
 
 	for p.peekTokenIs(token.Comma) {
 		p.nextToken()
 		p.nextToken()
-		i := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		is = append(is, i)
+		is = append(is, p.parseParameter())
 	}
 
 	if !p.expectPeek(token.Rparen) {
@@ -369,6 +904,16 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return is
 }
 
+// parseParameter parses a function parameter and its optional type.
+func (p *Parser) parseParameter() *ast.Identifier {
+	i := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.Colon) {
+		p.nextToken()
+		i.Type = p.parseTypeName()
+	}
+	return i
+}
+
 func (p *Parser) parseCallExpression(fun ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: fun}
 	exp.Arguments = p.parseExpressionList(token.Rparen)
@@ -379,6 +924,12 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	v, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if b, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = b
+			return lit
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken)
 		p.errors = append(p.errors, errors.New(msg))
@@ -390,6 +941,10 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseDecimalLiteral() ast.Expression {
+	return &ast.DecimalLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{
 		Token: p.curToken,
@@ -428,12 +983,63 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	// ** is right-associative: 2 ** 3 ** 2 == 2 ** (3 ** 2)
+	if p.curTokenIs(token.Power) {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+// parseRangeLiteral parses `start..end` and `start..<end`, optionally
+// followed by `step n`. step is not a keyword and is only recognized here.
+func (p *Parser) parseRangeLiteral(start ast.Expression) ast.Expression {
+	lit := &ast.RangeLiteral{
+		Token:     p.curToken,
+		Start:     start,
+		Inclusive: p.curTokenIs(token.DotDot),
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	lit.End = p.parseExpression(precedence)
+
+	if p.peekTokenIs(token.Ident) && p.peekToken.Literal == "step" {
+		p.nextToken()
+		p.nextToken()
+		lit.Step = p.parseExpression(precedence)
+	}
+
+	return lit
+}
+
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	return &ast.PostfixExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
+	}
+}
+
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		msg := fmt.Sprintf("cannot assign to %s", left)
+		p.errors = append(p.errors, errors.New(msg))
+		return nil
+	}
+
+	exp := &ast.AssignExpression{Token: p.curToken, Name: name}
+
+	p.nextToken()
+	// assignment is right-associative: a = b = 1 assigns 1 to both
+	exp.Value = p.parseExpression(Assign - 1)
+
+	return exp
+}
+
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
@@ -467,15 +1073,50 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseGroupedExpression parses a parenthesized expression, a tuple such
+// as (1, 2), (1,) or (), or the parameter list of an arrow function, which
+// can only be told apart by the arrow following the closing paren.
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	tok := p.curToken
+
+	var exps []ast.Expression
+	trailingComma := false
+	for !p.peekTokenIs(token.Rparen) {
+		p.nextToken()
+		exps = append(exps, p.parseExpression(LowSet))
+
+		trailingComma = false
+		if !p.peekTokenIs(token.Rparen) {
+			if !p.expectPeek(token.Comma) {
+				return nil
+			}
+			trailingComma = true
+		}
+	}
 	p.nextToken()
 
-	exp := p.parseExpression(LowSet)
-	if !p.expectPeek(token.Rparen) {
-		return nil
+	if p.peekTokenIs(token.Arrow) {
+		p.nextToken()
+
+		params := make([]*ast.Identifier, 0, len(exps))
+		for _, e := range exps {
+			ident, ok := e.(*ast.Identifier)
+			if !ok {
+				msg := fmt.Sprintf("arrow function parameter must be an identifier. got %s", e)
+				p.errors = append(p.errors, errors.New(msg))
+				return nil
+			}
+			params = append(params, ident)
+		}
+
+		return p.parseArrowFunction(params)
 	}
 
-	return exp
+	if len(exps) == 1 && !trailingComma {
+		return exps[0]
+	}
+
+	return &ast.TupleLiteral{Token: tok, Elements: exps}
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
@@ -486,9 +1127,16 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return array
 }
 
+func (p *Parser) parseSetLiteral() ast.Expression {
+	set := &ast.SetLiteral{Token: p.curToken}
+
+	set.Elements = p.parseExpressionList(token.Rbrace)
+
+	return set
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.Rbrace) {
 		p.nextToken()
@@ -501,7 +1149,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LowSet)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.Rbrace) && !p.expectPeek(token.Comma) {
 			return nil
@@ -539,6 +1187,17 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	return list
 }
 
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Left: left}
+
+	if !p.expectPeek(token.Ident) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 
@@ -196,6 +196,27 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "123456789012345678901234567890;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Big == nil {
+		t.Fatalf("literal.Big is nil")
+	}
+	if literal.Big.String() != "123456789012345678901234567890" {
+		t.Errorf("literal.Big wrong. got=%s", literal.Big)
+	}
+}
+
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world"`
 
@@ -215,6 +236,28 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestDecimalLiteralExpression(t *testing.T) {
+	input := `12.50d`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.DecimalLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.DecimalLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "12.50" {
+		t.Errorf("literal.Value not %q. got=%q", "12.50", literal.Value)
+	}
+	if literal.String() != "12.50d" {
+		t.Errorf("literal.String() not %q. got=%q", "12.50d", literal.String())
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 / 3]"
 
@@ -284,7 +327,8 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for k, v := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		k, v := pair.Key, pair.Value
 		strKey, ok := k.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key not *ast.StringLiteral. got=%T", k)
@@ -300,6 +344,65 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	}
 }
 
+func TestParsingSetLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#{}", "#{}"},
+		{"#{1, 2 * 2, \"a\"}", "#{1, (2 * 2), a}"},
+		{"#{x} | #{y}", "(#{x} | #{y})"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	l := lexer.New("#{1, 2}")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	set, ok := stmt.Expression.(*ast.SetLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.SetLiteral. got=%T", stmt.Expression)
+	}
+	if len(set.Elements) != 2 {
+		t.Fatalf("len(set.Elements) not 2. got=%d", len(set.Elements))
+	}
+	testIntegerLiteral(t, set.Elements[0], 1)
+	testIntegerLiteral(t, set.Elements[1], 2)
+}
+
+func TestParsingHashLiteralsOrder(t *testing.T) {
+	input := `{"c": 1, "a": 2, "b": 3, 1: 4}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression not *ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := "{c:1, a:2, b:3, 1:4}"
+	if hash.String() != expected {
+		t.Errorf("hash.String() wrong. expected=%q, got=%q", expected, hash.String())
+	}
+}
+
 func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	input := `{"one": 1*1, "two": 5-3, "three": 10-7}`
 
@@ -330,7 +433,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for k, v := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		k, v := pair.Key, pair.Value
 		strKey, ok := k.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key not *ast.StringLiteral. got=%T", k)
@@ -377,6 +481,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15", "-", 15},
+		{"~7", "~", 7},
 	}
 
 	for _, tt := range prefixTests {
@@ -423,6 +528,12 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
 		{"5 % 2;", 5, "%", 2},
+		{"5 ** 2;", 5, "**", 2},
+		{"5 & 2;", 5, "&", 2},
+		{"5 | 2;", 5, "|", 2},
+		{"5 ^ 2;", 5, "^", 2},
+		{"5 << 2;", 5, "<<", 2},
+		{"5 >> 2;", 5, ">>", 2},
 		{"true == true;", true, "==", true},
 		{"true != false;", true, "!=", false},
 		{"false == false;", false, "==", false},
@@ -482,6 +593,34 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"!(true == true)", "(!(true == true))"},
 		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"p.x.y * 2", "(((p.x).y) * 2)"},
+		{"-p.x", "(-(p.x))"},
+		{"p.add(q).x", "((p.add)(q).x)"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b | c & d", "((a & b) | (c & d))"},
+		{"a << 1 + 2", "(a << (1 + 2))"},
+		{"a & b == c", "((a & b) == c)"},
+		{"a < b << c", "(a < (b << c))"},
+		{"~a & b", "((~a) & b)"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"2 * 3 ** 2", "(2 * (3 ** 2))"},
+		{"-2 ** 2", "(-(2 ** 2))"},
+		{"2 ** -1", "(2 ** (-1))"},
+		{"1..n - 1", "(1..(n - 1))"},
+		{"0..<len(xs)", "(0..<len(xs))"},
+		{"x in 1..10", "(x in (1..10))"},
+		{"x in 0..<10 == true", "((x in (0..<10)) == true)"},
+		{"0..10 step 2 * 2", "(0..10 step (2 * 2))"},
+		{"x = 1 + 2", "(x = (1 + 2))"},
+		{"x = y = z", "(x = (y = z))"},
+		{"x = a == b", "(x = (a == b))"},
+		{"f(x = 1)", "f((x = 1))"},
+		{"f(x)?", "(f(x)?)"},
+		{"-f(x)?", "(-(f(x)?))"},
+		{"a + b? * c", "(a + ((b?) * c))"},
+		{"xs?[0]", "((xs?)[0])"},
+		{"map(xs, x => x * 2)", "map(xs, fn(x) (x * 2))"},
+		{"f((a, b) => a + b, c)", "f(fn(a, b) (a + b), c)"},
 	}
 
 	for _, tt := range tests {
@@ -650,6 +789,185 @@ default:
 	testIntegerLiteral(t, defaultStmt.Expression, 3)
 }
 
+func TestForStatement(t *testing.T) {
+	input := `for (x in xs) { x; };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+	if !testIdentifier(t, stmt.Iterable, "xs") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("stmt.Body.Statements does not contain 1 statement. got=%d", len(stmt.Body.Statements))
+	}
+	body := stmt.Body.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, body.Expression, "x")
+}
+
+func TestParsingRangeLiterals(t *testing.T) {
+	tests := []struct {
+		input     string
+		start     interface{}
+		end       interface{}
+		step      interface{}
+		inclusive bool
+	}{
+		{"1..10", 1, 10, nil, true},
+		{"0..<n", 0, "n", nil, false},
+		{"10..0 step -2", 10, 0, nil, true},
+		{"a..<b step c", "a", "b", "c", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		rng, ok := stmt.Expression.(*ast.RangeLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not *ast.RangeLiteral. got=%T", stmt.Expression)
+		}
+
+		testLiteralExpression(t, rng.Start, tt.start)
+		testLiteralExpression(t, rng.End, tt.end)
+		if tt.step != nil {
+			testLiteralExpression(t, rng.Step, tt.step)
+		}
+		if rng.Inclusive != tt.inclusive {
+			t.Errorf("rng.Inclusive not %t. got=%t", tt.inclusive, rng.Inclusive)
+		}
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	input := `defer close(f, 1);`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.DeferStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.DeferStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Call.Function, "close") {
+		return
+	}
+	if len(stmt.Call.Arguments) != 2 {
+		t.Fatalf("wrong length of arguments. expect=%d, got=%d", 2, len(stmt.Call.Arguments))
+	}
+	testLiteralExpression(t, stmt.Call.Arguments[0], "f")
+	testLiteralExpression(t, stmt.Call.Arguments[1], 1)
+
+	if program.String() != "defer close(f, 1);" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestDeferStatementError(t *testing.T) {
+	l := lexer.New("defer x + 1;")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+	if p.Errors()[0].Error() != "expression in defer must be function call" {
+		t.Errorf("wrong error. got=%q", p.Errors()[0].Error())
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (e) { g(e) }", "try f()catch (e) g(e)"},
+		{"try { f() } catch { g() }", "try f()catch g()"},
+		{"try { f() } finally { g() }", "try f()finally g()"},
+		{"try { f() } catch (e) { g(e) } finally { h() };", "try f()catch (e) g(e)finally h()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] not *ast.TryStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []string{
+		"try { f() }",
+		"try { f() } catch (1) { g() }",
+		"try { f() } catch (e { g() }",
+		"try f() catch { g() }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	l := lexer.New(`throw "bad input" + x;`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Value.String() != "(bad input + x)" {
+		t.Errorf("stmt.Value wrong. got=%q", stmt.Value.String())
+	}
+}
+
 func TestFunctionExpression(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -691,6 +1009,29 @@ func TestFunctionExpression(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value not *ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	if function.Name != "myFunction" {
+		t.Errorf("function literal name wrong. want 'myFunction', got=%q", function.Name)
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input        string
@@ -720,6 +1061,452 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestArrowFunctionParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectParams []string
+		expectBody   string
+	}{
+		{"x => x * 2", []string{"x"}, "(x * 2)"},
+		{"() => 1", []string{}, "1"},
+		{"(x) => x", []string{"x"}, "x"},
+		{"(a, b) => { a + b; }", []string{"a", "b"}, "(a + b)"},
+		{"(a, b) => { let c = a; c }", []string{"a", "b"}, "let c = a;c"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		fun, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+
+		if len(fun.Parameters) != len(tt.expectParams) {
+			t.Fatalf("wrong length of parameters. expect=%d, got=%d", len(tt.expectParams), len(fun.Parameters))
+		}
+		for i, ident := range tt.expectParams {
+			testLiteralExpression(t, fun.Parameters[i], ident)
+		}
+
+		if fun.Body.String() != tt.expectBody {
+			t.Errorf("fun.Body wrong. expect=%q, got=%q", tt.expectBody, fun.Body.String())
+		}
+	}
+}
+
+func TestAssignExpressionErrors(t *testing.T) {
+	tests := []string{
+		"1 = 2",
+		"f() = 2",
+		"(a + b) = 2",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestArrowFunctionParsingErrors(t *testing.T) {
+	tests := []string{
+		"(1) => 1",
+		"(a, 1) => a",
+		"(a,, b)",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestParsingTupleLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"()", "()"},
+		{"(1,)", "(1,)"},
+		{"(1, 2 * 3)", "(1, (2 * 3))"},
+		{"(1, (2, 3),)", "(1, (2, 3))"},
+		{"(1)", "1"},
+		{"((1, 2))", "(1, 2)"},
+		{"let (a, b) = (1, 2);", "let (a, b) = (1, 2);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{"let () = t;", "let (a, 1) = t;", "let (a b) = t;"} {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	input := `struct Point {
+	x, y
+	fn add(other) { Point(self.x + other.x, self.y + other.y) }
+	fn norm() { self.x * self.x + self.y * self.y }
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Name.Value != "Point" {
+		t.Errorf("stmt.Name.Value not %q. got=%q", "Point", stmt.Name.Value)
+	}
+	if len(stmt.Fields) != 2 || stmt.Fields[0].Value != "x" || stmt.Fields[1].Value != "y" {
+		t.Fatalf("stmt.Fields wrong. got=%v", stmt.Fields)
+	}
+	if len(stmt.Methods) != 2 {
+		t.Fatalf("stmt.Methods does not contain 2 methods. got=%d", len(stmt.Methods))
+	}
+	if stmt.Methods[0].Name != "add" || len(stmt.Methods[0].Parameters) != 1 {
+		t.Errorf("first method wrong. got=%s(%v)", stmt.Methods[0].Name, stmt.Methods[0].Parameters)
+	}
+	if stmt.Methods[1].Body.String() != "(((self.x) * (self.x)) + ((self.y) * (self.y)))" {
+		t.Errorf("second method body wrong. got=%s", stmt.Methods[1].Body.String())
+	}
+
+	l = lexer.New("struct Entry { name: string, amount }")
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt = program.Statements[0].(*ast.StructStatement)
+	if stmt.Fields[0].Type == nil || stmt.Fields[0].Type.Name != "string" || stmt.Fields[1].Type != nil {
+		t.Errorf("field annotations wrong. got=%v", stmt.Fields)
+	}
+}
+
+func TestStructOperatorMethods(t *testing.T) {
+	input := `struct Vec {
+	x, y
+	fn +(o) { Vec(self.x + o.x, self.y + o.y) }
+	fn ==(o) { self.x == o.x }
+	fn <(o) { self.x < o.x }
+	fn [](i) { self.x }
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+
+	expected := []string{"+", "==", "<", "[]"}
+	if len(stmt.Methods) != len(expected) {
+		t.Fatalf("stmt.Methods does not contain %d methods. got=%d", len(expected), len(stmt.Methods))
+	}
+	for i, name := range expected {
+		if stmt.Methods[i].Name != name {
+			t.Errorf("stmt.Methods[%d].Name not %q. got=%q", i, name, stmt.Methods[i].Name)
+		}
+	}
+}
+
+func TestStructStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct P { x, x }", "duplicate member x in struct P"},
+		{"struct P { x; fn x() {} }", "duplicate member x in struct P"},
+		{"struct P { 1 }", "unexpected 1 in struct P"},
+		{"struct P { fn !() {} }", "expected method name, got ! instead"},
+		{"struct P { fn +() {}; fn +(o) {} }", "duplicate member + in struct P"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		found := false
+		for _, err := range p.Errors() {
+			if err.Error() == tt.expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected parser error %q for %q. got=%v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestEnumStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Shape { Circle(r), Rect(w, h), Empty }", "enum Shape { Circle(r), Rect(w, h), Empty }"},
+		{"enum Color { Red; Green; Blue; }", "enum Color { Red, Green, Blue }"},
+		{"enum Never {}", "enum Never {  }"},
+		{"enum Color { Red };", "enum Color { Red }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.EnumStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] not *ast.EnumStatement. got=%T", program.Statements[0])
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum E { A, A(x) }", "duplicate variant A in enum E"},
+		{"enum E { 1 }", "unexpected 1 in enum E"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		found := false
+		for _, err := range p.Errors() {
+			if err.Error() == tt.expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected parser error %q for %q. got=%v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let f = fn(x: int, y) -> bool { x };", "let f = fn(x: int, y) -> bool x;"},
+		{"let g: fn = fn() -> null {};", "let g: fn = fn() -> null ;"},
+		{"let p: Point = q;", "let p: Point = q;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("fn(x: int) -> string { x }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fl := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if fl.Parameters[0].Value != "x" || fl.Parameters[0].Type.Name != "int" {
+		t.Errorf("parameter wrong. got=%s", fl.Parameters[0])
+	}
+	if fl.ReturnType == nil || fl.ReturnType.Name != "string" {
+		t.Errorf("return type wrong. got=%v", fl.ReturnType)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: 1 = 2;", "expected type name, got 1 instead"},
+		{"fn(x: ) {}", "expected type name, got ) instead"},
+		{"fn() -> {}", "expected type name, got { instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		found := false
+		for _, err := range p.Errors() {
+			if err.Error() == tt.expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected parser error %q for %q. got=%v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestYieldStatement(t *testing.T) {
+	input := `fn(n) {
+	for (i in 1..n) { yield i * 2; }
+	let f = fn() { 1 };
+	let g = () => { yield 1 };
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	outer := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if !outer.Generator {
+		t.Errorf("function containing yield not marked as generator")
+	}
+	loop := outer.Body.Statements[0].(*ast.ForStatement)
+	if loop.Body.Statements[0].String() != "yield (i * 2);" {
+		t.Errorf("yield statement wrong. got=%q", loop.Body.Statements[0].String())
+	}
+	f := outer.Body.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if f.Generator {
+		t.Errorf("nested function without yield marked as generator")
+	}
+	g := outer.Body.Statements[2].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if !g.Generator {
+		t.Errorf("arrow function containing yield not marked as generator")
+	}
+
+	l = lexer.New("yield 1;")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "yield outside function" {
+		t.Errorf("expected error for yield outside function. got=%v", p.Errors())
+	}
+}
+
+func TestSpawnExpression(t *testing.T) {
+	l := lexer.New("let t = spawn f(x, 1);")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	exp, ok := program.Statements[0].(*ast.LetStatement).Value.(*ast.SpawnExpression)
+	if !ok {
+		t.Fatalf("let value not *ast.SpawnExpression. got=%T", program.Statements[0].(*ast.LetStatement).Value)
+	}
+	if exp.String() != "spawn f(x, 1)" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+
+	l = lexer.New("spawn f;")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "expression in spawn must be function call" {
+		t.Errorf("expected error for spawn without call. got=%v", p.Errors())
+	}
+}
+
+func TestSelectStatement(t *testing.T) {
+	input := `select {
+case v = receive(a):
+	v
+case receive(b):
+case send(c, 1):
+	1; 2
+default:
+	3
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	ss, ok := program.Statements[0].(*ast.SelectStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] not *ast.SelectStatement. got=%T", program.Statements[0])
+	}
+	if len(ss.Cases) != 3 {
+		t.Fatalf("ss.Cases does not contain 3 cases. got=%d", len(ss.Cases))
+	}
+	if ss.Cases[0].Name == nil || ss.Cases[0].Name.Value != "v" || ss.Cases[0].Send {
+		t.Errorf("first case wrong. got=%+v", ss.Cases[0])
+	}
+	if ss.Cases[1].Name != nil || len(ss.Cases[1].Block) != 0 {
+		t.Errorf("second case wrong. got=%+v", ss.Cases[1])
+	}
+	if !ss.Cases[2].Send || len(ss.Cases[2].Block) != 2 {
+		t.Errorf("third case wrong. got=%+v", ss.Cases[2])
+	}
+	if len(ss.Default) != 1 {
+		t.Errorf("default does not contain 1 statement. got=%d", len(ss.Default))
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"select { case f(a): 1 }", "select case must be send(ch, v), receive(ch) or name = receive(ch)"},
+		{"select { case v = send(a, 1): 1 }", "select case must be send(ch, v), receive(ch) or name = receive(ch)"},
+		{"select { 1 }", "expected case or default in select, got INT instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0].Error() != tt.expected {
+			t.Errorf("wrong errors for %q. expected=%q, got=%v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := `add(1, 2 * 3, 4 + 5)`
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/yuzuy/yoru/checker"
 	"github.com/yuzuy/yoru/evaluator"
 	"github.com/yuzuy/yoru/lexer"
 	"github.com/yuzuy/yoru/object"
@@ -16,6 +17,7 @@ const PROMPT = ">> "
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
+	check := checker.New()
 
 	for {
 		fmt.Print(PROMPT)
@@ -34,11 +36,20 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
+		_, warnings := check.Check(program)
+		for _, w := range warnings {
+			io.WriteString(out, "warning: "+w.Error()+"\n")
+		}
+
 		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
+		if err, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, err.StackTrace())
+			io.WriteString(out, err.GoStack)
+		}
 	}
 }
 
@@ -1,19 +1,36 @@
 package token
 
+import "fmt"
+
 type Type string
 
 type Token struct {
 	Type    Type
 	Literal string
+	Pos     Position
+}
+
+// Position is a 1-based line and column in the source.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position was set by the lexer.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
 	Illegal = "ILLEGAL"
 	EOF     = "EOF"
 
-	Ident  = "IDENT"
-	Int    = "INT"
-	String = "STRING"
+	Ident   = "IDENT"
+	Int     = "INT"
+	Decimal = "DECIMAL"
+	String  = "STRING"
 
 	Assign   = "="
 	Plus     = "+"
@@ -22,6 +39,14 @@ const (
 	Asterisk = "*"
 	Slash    = "/"
 	Mod      = "%"
+	Power    = "**"
+
+	Ampersand = "&"
+	Pipe      = "|"
+	Caret     = "^"
+	Tilde     = "~"
+	LShift    = "<<"
+	RShift    = ">>"
 
 	LT = "<"
 	GT = ">"
@@ -29,6 +54,14 @@ const (
 	EQ    = "=="
 	NotEQ = "!="
 
+	Arrow     = "=>"
+	ThinArrow = "->"
+
+	Dot      = "."
+	DotDot   = ".."
+	DotDotLT = "..<"
+
+	Question  = "?"
 	Comma     = ","
 	Colon     = ":"
 	Semicolon = ";"
@@ -37,6 +70,7 @@ const (
 	Rparen   = ")"
 	Lbrace   = "{"
 	Rbrace   = "}"
+	SetBrace = "#{"
 	LBracket = "["
 	RBracket = "]"
 
@@ -51,6 +85,18 @@ const (
 	Default  = "DEFAULT"
 	Return   = "RETURN"
 	Null     = "NULL"
+	For      = "FOR"
+	In       = "IN"
+	Defer    = "DEFER"
+	Try      = "TRY"
+	Catch    = "CATCH"
+	Finally  = "FINALLY"
+	Throw    = "THROW"
+	Struct   = "STRUCT"
+	Enum     = "ENUM"
+	Yield    = "YIELD"
+	Spawn    = "SPAWN"
+	Select   = "SELECT"
 )
 
 var keywords = map[string]Type{
@@ -65,6 +111,18 @@ var keywords = map[string]Type{
 	"default": Default,
 	"ret":     Return,
 	"null":    Null,
+	"for":     For,
+	"in":      In,
+	"defer":   Defer,
+	"try":     Try,
+	"catch":   Catch,
+	"finally": Finally,
+	"throw":   Throw,
+	"struct":  Struct,
+	"enum":    Enum,
+	"yield":   Yield,
+	"spawn":   Spawn,
+	"select":  Select,
 }
 
 func LookUpIdent(ident string) Type {